@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	struct_validator "github.com/go-playground/validator/v10"
+	"os"
+	"static-openapivalidator/logger"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/spec"
+	"strings"
+)
+
+// Execute writes the spec as validated, overlays applied, in a single document
+func (params *BundleParams) Execute() error {
+	logger.Enabled = params.Debug
+
+	if err := struct_validator.New().Struct(params); err != nil {
+		return errors.New("invalid input: " + err.Error())
+	}
+
+	config := specFlag(params.ApiFilePath)
+	if config.Name == "" {
+		config.Name = specName(config.Location)
+	}
+	loading := Params{
+		Ctx:          params.Ctx,
+		SpecHeaders:  params.SpecHeaders,
+		SpecCacheDir: params.SpecCacheDir,
+		Overlays:     params.Overlays,
+	}
+	logger.Log("OpenAPI Spec %s: loading", config.Name)
+	routed := routing.Spec{}
+	doc, err := loading.loadSpec(config, &routed)
+	if err != nil {
+		return errors.New(config.Name + ": " + err.Error())
+	}
+	// Only OpenAPI 3.1 specs validate their bodies apart, doc is the lossy 3.0 version kin-openapi loads of them
+	if routed.BodyValidator != nil {
+		return errors.New(config.Name + ": OpenAPI 3.1 specs cannot be bundled, they are only loaded as OpenAPI 3.0")
+	}
+
+	logger.Log("OpenAPI Spec %s: bundling", config.Name)
+	sources := spec.Bundle(params.Ctx, doc, config.Location)
+	var data []byte
+	if params.Dereference {
+		data, err = spec.Dereference(doc)
+	} else {
+		data, err = json.Marshal(doc)
+	}
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(params.OutputPath, ".json") {
+		data, err = json.MarshalIndent(json.RawMessage(data), "", "  ")
+	} else {
+		data, err = spec.Marshal(data)
+	}
+	if err != nil {
+		return err
+	}
+	if params.OutputPath == "" {
+		fmt.Print(string(data))
+	} else if err = os.WriteFile(params.OutputPath, data, 0644); err != nil {
+		return err
+	}
+
+	if params.SourceMapPath != "" {
+		logger.Log("OpenAPI Spec %s: writing the source map to %s", config.Name, params.SourceMapPath)
+		sourceMap, err := json.MarshalIndent(sources, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(params.SourceMapPath, sourceMap, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
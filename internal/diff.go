@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	struct_validator "github.com/go-playground/validator/v10"
+	"os"
+	"sort"
+	"static-openapivalidator/logger"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/spec"
+	"static-openapivalidator/validator"
+)
+
+// Execute lists the changes from the base spec to the revision, and fails on the breaking ones
+func (params *DiffParams) Execute() error {
+	logger.Enabled = params.Debug
+
+	if err := struct_validator.New().Struct(params); err != nil {
+		return errors.New("invalid input: " + err.Error())
+	}
+
+	loading := Params{
+		Ctx:             params.Ctx,
+		SpecHeaders:     params.SpecHeaders,
+		SpecCacheDir:    params.SpecCacheDir,
+		Overlays:        params.Overlays,
+		ReportFilePaths: params.ReportFilePaths,
+		Format:          params.Format,
+		Environment:     params.Environment,
+		ConfigFilePath:  params.ConfigFilePath,
+	}
+	logger.Log("Loading configuration from file")
+	if err := loading.loadConfig(); err != nil {
+		return err
+	}
+
+	base, baseRouted, err := loading.loadVersion(params.BaseFilePath)
+	if err != nil {
+		return err
+	}
+	revision, _, err := loading.loadVersion(params.RevisionFilePath)
+	if err != nil {
+		return err
+	}
+
+	logger.Log("Diff: comparing %s with %s", base.File, revision.File)
+	changes := spec.Diff(base, revision)
+	if len(params.ReportFilePaths) > 0 {
+		if err = loading.rankChanges(changes, specFlag(params.BaseFilePath), base.Doc, baseRouted); err != nil {
+			return err
+		}
+	}
+
+	breaking := 0
+	for _, change := range changes {
+		if change.Breaking {
+			breaking++
+		}
+	}
+	printChanges(changes, breaking, len(params.ReportFilePaths) > 0)
+
+	if params.OutputPath != "" {
+		logger.Log("Diff: writing the changes to %s", params.OutputPath)
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(params.OutputPath, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if breaking > 0 {
+		return fmt.Errorf("breaking changes: %d", breaking)
+	}
+	return nil
+}
+
+// loadVersion loads a spec to compare, it returns its routing setup as well
+func (params *Params) loadVersion(location string) (spec.Version, routing.Spec, error) {
+	config := specFlag(location)
+	if config.Name == "" {
+		config.Name = specName(config.Location)
+	}
+	logger.Log("OpenAPI Spec %s: loading", config.Name)
+	routed := routing.Spec{Name: config.Name}
+	doc, err := params.loadSpec(config, &routed)
+	if err != nil {
+		return spec.Version{}, routing.Spec{}, errors.New(config.Name + ": " + err.Error())
+	}
+	return spec.Version{Doc: doc, File: config.Location, Locations: routed.Locations}, routed, nil
+}
+
+// rankChanges counts the recorded exchanges of the operation and response of each change, and sorts the breaking changes by count
+// The exchanges are routed with the base spec, as they were recorded against it
+func (params *Params) rankChanges(changes []spec.Change, config Spec, doc *openapi3.T, routed routing.Spec) error {
+	if config.Name == "" {
+		config.Name = specName(config.Location)
+	}
+	if err := params.routeSpec(config, doc, &routed); err != nil {
+		return errors.New(config.Name + ": " + err.Error())
+	}
+	router := routing.NewRouter(routed)
+	router.Rewrite = params.rewrite
+
+	logger.Log("%s: getting parser", params.Format)
+	parser, err := params.getParser()
+	if err != nil {
+		return err
+	}
+	logger.Log("%s: parsing results from files", params.Format)
+	results, err := parser.Parse(params.ReportFilePaths, router, params.config)
+	if err != nil {
+		return err
+	}
+
+	exchanges := make(map[string]int)
+	for _, result := range results {
+		if result.Request == nil || result.Request.Route == nil || result.Request.Ignored {
+			continue
+		}
+		route := result.Request.Route
+		exchanges[route.Method+" "+route.Path]++
+		if result.Response != nil && result.Response.TransportError == "" {
+			exchanges[route.Method+" "+route.Path+" "+validator.ResponseStatus(route.Operation, result.Response.Status)]++
+		}
+	}
+	for i := range changes {
+		key := changes[i].Method + " " + changes[i].Path
+		if changes[i].Status != "" {
+			key += " " + changes[i].Status
+		}
+		changes[i].Exchanges = exchanges[key]
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Breaking != changes[j].Breaking {
+			return changes[i].Breaking
+		}
+		return changes[i].Breaking && changes[i].Exchanges > changes[j].Exchanges
+	})
+	return nil
+}
+
+func printChanges(changes []spec.Change, breaking int, ranked bool) {
+	fmt.Printf("Breaking changes: %d\n", breaking)
+	for _, change := range changes[:breaking] {
+		if ranked {
+			fmt.Printf("  [%d exchanges] %s\n", change.Exchanges, change)
+		} else {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+	fmt.Printf("Non-breaking changes: %d\n", len(changes)-breaking)
+	for _, change := range changes[breaking:] {
+		fmt.Printf("  %s\n", change)
+	}
+}
@@ -1,21 +1,28 @@
 package internal
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"github.com/getkin/kin-openapi/openapi3"
-	"github.com/getkin/kin-openapi/routers/gorillamux"
 	struct_validator "github.com/go-playground/validator/v10"
 	"github.com/gobwas/glob"
 	"gopkg.in/yaml.v3"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"static-openapivalidator/logger"
 	test_report "static-openapivalidator/parser"
 	"static-openapivalidator/reports"
 	"static-openapivalidator/reports/html"
 	"static-openapivalidator/reports/json"
 	"static-openapivalidator/reports/junit"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/spec"
 	"static-openapivalidator/validator"
+	"strings"
 )
 
 func (params *Params) Execute() error {
@@ -46,6 +53,13 @@ func (params *Params) Execute() error {
 	}
 
 	fmt.Println(*summary)
+	// Against a candidate spec, only the regressions fail the run
+	if summary.Candidate != nil {
+		if summary.Candidate.Failed() {
+			return errors.New("run failed")
+		}
+		return nil
+	}
 	if summary.FailedResponses > 0 || summary.FailedRequests > 0 {
 		return errors.New("run failed")
 	}
@@ -85,6 +99,17 @@ func (params *Params) loadConfig() error {
 			IgnoredResponses: bannedResponses,
 			IgnoredRoutes:    bannedRoutes,
 			IgnoreServers:    config.Ignore.Servers,
+			IdTemplate:       config.Ids.Template,
+		}
+		params.specs = config.Specs
+		params.servers = config.Servers
+		params.router = routing.Options{
+			Implementation:      config.Router.Type,
+			IgnoreTrailingSlash: config.Router.IgnoreTrailingSlash,
+			CaseInsensitive:     config.Router.CaseInsensitive,
+		}
+		if params.rewrite, err = compileRewrite(config.Rewrite); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -102,48 +127,407 @@ func compileGlobs(array []string) ([]glob.Glob, error) {
 	return result, nil
 }
 
+// compileRewrite compiles the rewrite rules of the configuration, it returns nil when there is none
+func compileRewrite(config Rewrite) (*routing.Rewrite, error) {
+	if len(config.Hosts) == 0 && len(config.Paths) == 0 && len(config.Query) == 0 && len(config.Headers.Set) == 0 && len(config.Headers.Remove) == 0 {
+		return nil, nil
+	}
+	rewrite := &routing.Rewrite{
+		Hosts:         config.Hosts,
+		SetHeaders:    config.Headers.Set,
+		RemoveHeaders: config.Headers.Remove,
+	}
+	for _, rule := range config.Paths {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, errors.New("rewrite: " + err.Error())
+		}
+		rewrite.Paths = append(rewrite.Paths, routing.PathRewrite{Pattern: pattern, Replacement: rule.Replacement})
+	}
+	var err error
+	if rewrite.StripQuery, err = compileGlobs(config.Query); err != nil {
+		return nil, errors.New("rewrite: " + err.Error())
+	}
+	return rewrite, nil
+}
+
 func (params *Params) checkResponses() ([]validator.ValidationResult, error) {
-	// Load open api ref
-	logger.Log("OpenAPI Spec: loading")
-	loader := &openapi3.Loader{Context: params.Ctx, IsExternalRefsAllowed: true}
-	doc, err := loader.LoadFromFile(params.ApiFilePath)
+	specs, err := params.loadSpecs()
 	if err != nil {
 		return nil, err
 	}
+	router := routing.NewRouter(specs...)
+	router.Rewrite = params.rewrite
 
-	logger.Log("OpenAPI Spec: validating")
-	// Validate document
-	err = doc.Validate(params.Ctx)
+	// Parse file
+	logger.Log("%s: getting parser", params.Format)
+	parser, err := params.getParser()
 	if err != nil {
-		return nil, errors.New("openapi validation: " + err.Error())
+		return nil, err
 	}
 
+	logger.Log("%s: parsing results from files", params.Format)
+	results, err := parser.Parse(params.ReportFilePaths, router, params.config)
+	if err != nil {
+		return nil, err
+	}
+	// The results are copied before validating, as validation reads their bodies
+	var candidates []validator.TestResult
+	if len(params.CandidateSpecPaths) > 0 {
+		candidateSpecs, err := params.loadCandidateSpecs()
+		if err != nil {
+			return nil, err
+		}
+		candidateRouter := routing.NewRouter(candidateSpecs...)
+		candidateRouter.Rewrite = params.rewrite
+		logger.Log("%s: routing results with the candidate specs", params.Format)
+		if candidates, err = test_report.Reroute(results, candidateRouter); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate all request/responses
+	openapi3.SchemaErrorDetailsDisabled = true
+	validated, err := validator.Validate(results, params.Ctx)
+	if err != nil || candidates == nil {
+		return validated, err
+	}
+	logger.Log("Validator: validating results against the candidate specs")
+	candidateValidated, err := validator.Validate(candidates, params.Ctx)
+	if err != nil {
+		return nil, err
+	}
+	return validated, validator.Compare(validated, candidateValidated)
+}
+
+// loadSpecs loads the specs given as flags, then the ones of the configuration file
+func (params *Params) loadSpecs() ([]routing.Spec, error) {
+	configs, err := params.specConfigs()
+	if err != nil {
+		return nil, err
+	}
+	return params.loadRoutedSpecs(configs)
+}
+
+// loadCandidateSpecs loads the specs with the candidate ones in place of the specs of the same name
+// A candidate keeps the bindings of the spec it replaces, the others are added to the specs
+// A single unnamed candidate replaces a single spec, whatever its file name
+func (params *Params) loadCandidateSpecs() ([]routing.Spec, error) {
+	configs, err := params.specConfigs()
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range params.CandidateSpecPaths {
+		candidate := specFlag(location)
+		if candidate.Name == "" && len(configs) == 1 && len(params.CandidateSpecPaths) == 1 {
+			candidate.Name = configs[0].Name
+		} else if candidate.Name == "" {
+			candidate.Name = specName(candidate.Location)
+		}
+		candidate.candidate = true
+		replaced := false
+		for i := range configs {
+			if configs[i].Name == candidate.Name {
+				if configs[i].candidate {
+					return nil, fmt.Errorf("candidate spec %s is given twice, name them apart", candidate.Name)
+				}
+				configs[i].Location = candidate.Location
+				configs[i].candidate = true
+				replaced = true
+			}
+		}
+		if !replaced {
+			configs = append(configs, candidate)
+		}
+	}
+	specs, err := params.loadRoutedSpecs(configs)
+	if err != nil {
+		return nil, errors.New("candidate spec " + err.Error())
+	}
+	return specs, nil
+}
+
+// specConfigs lists the specs given as flags, then the ones of the configuration file, all named
+func (params *Params) specConfigs() ([]Spec, error) {
+	var configs []Spec
+	for _, location := range params.ApiFilePaths {
+		configs = append(configs, specFlag(location))
+	}
+	configs = append(configs, params.specs...)
+	if len(configs) == 0 {
+		return nil, errors.New("invalid input: at least one spec is required")
+	}
+
+	names := make(map[string]bool)
+	for i := range configs {
+		if configs[i].Name == "" {
+			configs[i].Name = specName(configs[i].Location)
+		}
+		if names[configs[i].Name] {
+			return nil, fmt.Errorf("spec %s is given twice, name them apart", configs[i].Name)
+		}
+		names[configs[i].Name] = true
+	}
+	return configs, nil
+}
+
+func (params *Params) loadRoutedSpecs(configs []Spec) ([]routing.Spec, error) {
+	var specs []routing.Spec
+	for _, config := range configs {
+		routed, err := params.loadRoutedSpec(config)
+		if err != nil {
+			return nil, errors.New(config.Name + ": " + err.Error())
+		}
+		specs = append(specs, routed)
+	}
+	return specs, nil
+}
+
+// specFlag parses a spec given as a flag, either a location, or "name=location" to name the spec
+func specFlag(location string) Spec {
+	if name, rest, found := strings.Cut(location, "="); found && !strings.ContainsAny(name, `/\:`) {
+		return Spec{Name: name, Location: rest}
+	}
+	return Spec{Location: location}
+}
+
+// specName names a spec after its file
+func specName(location string) string {
+	if parsed, err := url.Parse(location); err == nil && spec.IsRemote(location) {
+		location = parsed.Path
+	}
+	base := path.Base(filepath.ToSlash(location))
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+func (params *Params) loadRoutedSpec(config Spec) (routing.Spec, error) {
+	// Load open api ref
+	logger.Log("OpenAPI Spec %s: loading", config.Name)
+	routed := routing.Spec{
+		Name:   config.Name,
+		Prefix: config.Prefix,
+		Strip:  config.Strip,
+	}
+	doc, err := params.loadSpec(config, &routed)
+	if err != nil {
+		return routing.Spec{}, err
+	}
+	if err = params.routeSpec(config, doc, &routed); err != nil {
+		return routing.Spec{}, err
+	}
+	return routed, nil
+}
+
+// routeSpec sets up the routing of the exchanges to a loaded spec, from its bindings
+func (params *Params) routeSpec(config Spec, doc *openapi3.T, routed *routing.Spec) error {
+	var err error
 	if params.config.IgnoreServers {
 		// Ignoring servers from spec so requests on any host matches
-		logger.Log("OpenAPI Spec: enabling IgnoreServers")
+		logger.Log("OpenAPI Spec %s: enabling IgnoreServers", config.Name)
 		doc.Servers = openapi3.Servers{}
+	} else if err = params.prepareServers(config, doc, routed); err != nil {
+		return err
+	}
+
+	if routed.Router, err = routing.NewSpecRouter(doc, params.router); err != nil {
+		return err
+	}
+	if config.Host != "" {
+		if routed.Host, err = glob.Compile(config.Host); err != nil {
+			return err
+		}
+	}
+	if config.Header != "" {
+		name, value, found := strings.Cut(config.Header, ":")
+		if !found {
+			return fmt.Errorf("invalid header binding %q, expected \"Name: glob\"", config.Header)
+		}
+		routed.HeaderName = strings.TrimSpace(name)
+		if routed.HeaderValue, err = glob.Compile(strings.TrimSpace(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareServers sets up the matching of the servers of a spec, for the mode of the spec or of the configuration
+func (params *Params) prepareServers(config Spec, doc *openapi3.T, routed *routing.Spec) error {
+	servers := params.servers
+	if config.Servers != nil {
+		servers = *config.Servers
+	}
+	logger.Log("OpenAPI Spec %s: matching servers in %s mode", config.Name, cmp.Or(servers.Mode, routing.ServersExact))
+
+	var err error
+	if routed.ServerURLs, err = routing.PrepareServers(doc, servers.Mode); err != nil {
+		return err
+	}
+	if servers.Mode != routing.ServersMap {
+		if len(servers.Hosts) > 0 {
+			return fmt.Errorf("servers hosts are only used in the %s mode", routing.ServersMap)
+		}
+		return nil
+	}
+	if len(servers.Hosts) == 0 {
+		return fmt.Errorf("servers mode %s requires hosts", routing.ServersMap)
 	}
+	routed.Hosts, err = routing.ParseHosts(servers.Hosts)
+	return err
+}
 
-	router, err := gorillamux.NewRouter(doc)
+// loadSpec loads a spec from a file or an HTTP URL, with its overlays, through the parse cache when enabled
+func (params *Params) loadSpec(config Spec, routed *routing.Spec) (*openapi3.T, error) {
+	loader, location, err := params.newSpecLoader(config.Location)
 	if err != nil {
 		return nil, err
 	}
-
-	// Parse file
-	logger.Log("%s: getting parser", params.Format)
-	parser, err := getParser(params.Format)
+	data, overlays, err := params.readSpec(config, loader, location)
 	if err != nil {
 		return nil, err
 	}
+	if params.ParseCacheDir == "" {
+		doc, _, err := params.parseSpec(config.Name, location, data, loader, loader.ReadFromURIFunc, routed)
+		return doc, err
+	}
 
-	logger.Log("%s: parsing results from files", params.Format)
-	results, err := parser.Parse(params.ReportFilePaths, router, params.config)
+	cache := &spec.ParseCache{Dir: params.ParseCacheDir}
+	cacheKey := strings.Join(append([]string{config.Location}, overlays...), "\n")
+	if cached, ok := cache.Load(cacheKey, location, data, loader, loader.ReadFromURIFunc); ok {
+		logger.Log("OpenAPI Spec %s: loaded from the parse cache", config.Name)
+		if err = routeSpecKind(cached.Kind, location, data, loader, cached.Read, routed); err != nil {
+			return nil, err
+		}
+		if cached.Locations != nil {
+			routed.Locations = cached.Locations
+		}
+		return cached.Doc, nil
+	}
+	recording := spec.NewRecording(location, data)
+	doc, kind, err := params.parseSpec(config.Name, location, data, loader, recording.Files(loader.ReadFromURIFunc), routed)
 	if err != nil {
 		return nil, err
 	}
-	// Validate all request/responses
-	openapi3.SchemaErrorDetailsDisabled = true
-	return validator.Validate(results, params.Ctx)
+	if locations := cache.Store(params.Ctx, cacheKey, location, kind, doc, recording); locations != nil {
+		routed.Locations = locations
+	}
+	return doc, nil
+}
+
+// readSpec reads the spec at location and applies its overlays, it returns the spec and the overlays applied
+// The effective spec is exported if asked to
+func (params *Params) readSpec(config Spec, loader *openapi3.Loader, location *url.URL) ([]byte, []string, error) {
+	data, err := loader.ReadFromURIFunc(loader, location)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overlays := append(append([]string{}, params.Overlays...), config.Overlays...)
+	if len(overlays) > 0 {
+		logger.Log("OpenAPI Spec %s: applying %d overlays", config.Name, len(overlays))
+		if data, err = spec.ApplyOverlays(data, location, loader, overlays); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if params.ExportSpecDir != "" {
+		exportName := config.Name
+		if config.candidate {
+			exportName += ".candidate"
+		}
+		exportPath := filepath.Join(params.ExportSpecDir, exportName+".yaml")
+		logger.Log("OpenAPI Spec %s: exporting to %s", config.Name, exportPath)
+		if err = os.MkdirAll(params.ExportSpecDir, 0755); err != nil {
+			return nil, nil, err
+		}
+		exported, err := spec.Marshal(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = os.WriteFile(exportPath, exported, 0644); err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, overlays, nil
+}
+
+// parseSpec loads and validates the spec at location, whose content is data, external references are read with read
+// OpenAPI 3.1 specs are loaded as their 3.0 version, Swagger 2.0 specs converted to OpenAPI 3.0, it returns the kind of the spec
+func (params *Params) parseSpec(name string, location *url.URL, data []byte, loader *openapi3.Loader, read openapi3.ReadFromURIFunc, routed *routing.Spec) (*openapi3.T, string, error) {
+	kind := spec.KindOpenAPI30
+	if spec.IsSwagger2(data) {
+		logger.Log("OpenAPI Spec %s: Swagger 2.0, converting to OpenAPI 3.0", name)
+		kind = spec.KindSwagger2
+	} else if spec.IsOpenAPI31(data) {
+		logger.Log("OpenAPI Spec %s: OpenAPI 3.1, validating bodies as JSON Schema 2020-12", name)
+		kind = spec.KindOpenAPI31
+	}
+	if err := routeSpecKind(kind, location, data, loader, read, routed); err != nil {
+		return nil, "", err
+	}
+
+	var err error
+	loader.ReadFromURIFunc = read
+	if kind == spec.KindOpenAPI31 {
+		loader.ReadFromURIFunc = func(loader *openapi3.Loader, uri *url.URL) ([]byte, error) {
+			referenced, err := read(loader, uri)
+			if err != nil {
+				return nil, err
+			}
+			return spec.Downgrade(referenced)
+		}
+		if data, err = spec.Downgrade(data); err != nil {
+			return nil, "", err
+		}
+	}
+	var doc *openapi3.T
+	if kind == spec.KindSwagger2 {
+		if doc, err = spec.ConvertSwagger2(data, loader, location); err != nil {
+			return nil, "", errors.New("swagger 2.0 conversion: " + err.Error())
+		}
+	} else if doc, err = loader.LoadFromDataWithPath(data, location); err != nil {
+		return nil, "", err
+	}
+
+	logger.Log("OpenAPI Spec %s: validating", name)
+	if err = doc.Validate(params.Ctx); err != nil {
+		return nil, "", errors.New("openapi validation: " + err.Error())
+	}
+	return doc, kind, nil
+}
+
+// routeSpecKind sets up routed for the kind of the spec at location, whose content is data
+// The bodies of OpenAPI 3.1 specs are validated as JSON Schema, the errors of Swagger 2.0 specs are located in the original spec
+func routeSpecKind(kind string, location *url.URL, data []byte, loader *openapi3.Loader, read openapi3.ReadFromURIFunc, routed *routing.Spec) error {
+	var err error
+	switch kind {
+	case spec.KindSwagger2:
+		routed.Locations = spec.Swagger2Locations
+	case spec.KindOpenAPI31:
+		routed.BodyValidator, err = spec.NewSchemas(location, data, func(uri *url.URL) ([]byte, error) {
+			return read(loader, uri)
+		})
+	}
+	return err
+}
+
+// newSpecLoader returns a loader for the spec at location, fetching remote documents when needed
+func (params *Params) newSpecLoader(location string) (*openapi3.Loader, *url.URL, error) {
+	loader := &openapi3.Loader{Context: params.Ctx, IsExternalRefsAllowed: true}
+	if !spec.IsRemote(location) {
+		loader.ReadFromURIFunc = openapi3.DefaultReadFromURI
+		return loader, &url.URL{Path: filepath.ToSlash(location)}, nil
+	}
+
+	remote, err := spec.NewRemote(location, params.SpecHeaders, params.SpecCacheDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	loader.ReadFromURIFunc = openapi3.URIMapCache(openapi3.ReadFromURIs(remote.ReadFromURI, openapi3.ReadFromFile))
+	return loader, parsed, nil
 }
 
 func (params *Params) logResults(results []validator.ValidationResult) (*reports.Summary, error) {
@@ -179,13 +563,17 @@ func (params *Params) logResults(results []validator.ValidationResult) (*reports
 	return &report.Summary, nil
 }
 
-func getParser(format string) (test_report.Parser, error) {
-	switch format {
+func (params *Params) getParser() (test_report.Parser, error) {
+	switch params.Format {
 	case "bruno":
 		return test_report.BrunoParser{}, nil
 	case "postman":
 		return test_report.PostmanParser{}, nil
+	case "postman-collection":
+		return test_report.PostmanCollectionParser{Environment: params.Environment}, nil
+	case "bruno-collection":
+		return test_report.BrunoCollectionParser{Environment: params.Environment}, nil
 	default:
-		return nil, fmt.Errorf("format %s not supported", format)
+		return nil, fmt.Errorf("format %s not supported", params.Format)
 	}
 }
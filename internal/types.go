@@ -2,24 +2,130 @@ package internal
 
 import (
 	"context"
+	"static-openapivalidator/routing"
 	"static-openapivalidator/validator"
 )
 
 type Params struct {
-	Ctx             context.Context
-	ApiFilePath     string   `validate:"required,file"`
-	ReportFilePaths []string `validate:"gt=0,dive,file"`
-	Format          string   `validate:"required"`
-	JunitFilePath   string   `validate:"omitempty,filepath"`
-	HtmlFilePath    string   `validate:"omitempty,filepath"`
-	JsonFilePath    string   `validate:"omitempty,filepath"`
-	ConfigFilePath  string   `validate:"omitempty,file"`
-	Debug           bool
-	config          validator.Config
+	Ctx          context.Context
+	ApiFilePaths []string `validate:"dive,required"`
+	// CandidateSpecPaths replace the specs of the same name, or are added to them, to compare their results with the current ones
+	CandidateSpecPaths []string `validate:"dive,required"`
+	SpecHeaders        []string
+	SpecCacheDir       string
+	ParseCacheDir      string
+	Overlays           []string `validate:"dive,file"`
+	ExportSpecDir      string
+	ReportFilePaths    []string `validate:"gt=0,dive,file|dir"`
+	Format             string   `validate:"required"`
+	JunitFilePath      string   `validate:"omitempty,filepath"`
+	HtmlFilePath       string   `validate:"omitempty,filepath"`
+	JsonFilePath       string   `validate:"omitempty,filepath"`
+	ConfigFilePath     string   `validate:"omitempty,file"`
+	Environment        string
+	Debug              bool
+	config             validator.Config
+	specs              []Spec
+	servers            Servers
+	rewrite            *routing.Rewrite
+	router             routing.Options
+}
+
+// BundleParams are the parameters of the bundle command, the spec is loaded as for a validation
+type BundleParams struct {
+	Ctx          context.Context
+	ApiFilePath  string `validate:"required"`
+	SpecHeaders  []string
+	SpecCacheDir string
+	Overlays     []string `validate:"dive,file"`
+	// OutputPath is written as JSON when it ends with .json, as YAML otherwise, and to the standard output when empty
+	OutputPath    string `validate:"omitempty,filepath"`
+	SourceMapPath string `validate:"omitempty,filepath"`
+	Dereference   bool
+	Debug         bool
+}
+
+// DiffParams are the parameters of the diff command, both specs are loaded as for a validation
+type DiffParams struct {
+	Ctx              context.Context
+	BaseFilePath     string `validate:"required"`
+	RevisionFilePath string `validate:"required"`
+	SpecHeaders      []string
+	SpecCacheDir     string
+	Overlays         []string `validate:"dive,file"`
+	// ReportFilePaths rank the breaking changes by recorded exchanges when given, routed with the base spec
+	ReportFilePaths []string `validate:"dive,file|dir"`
+	Format          string
+	Environment     string
+	ConfigFilePath  string `validate:"omitempty,file"`
+	// OutputPath receives the changes as JSON when set
+	OutputPath string `validate:"omitempty,filepath"`
+	Debug      bool
 }
 
 type Config struct {
-	Ignore Ignore `yaml:"ignore"`
+	Ignore  Ignore  `yaml:"ignore"`
+	Ids     Ids     `yaml:"ids"`
+	Specs   []Spec  `yaml:"specs"`
+	Servers Servers `yaml:"servers"`
+	Rewrite Rewrite `yaml:"rewrite"`
+	Router  Router  `yaml:"router"`
+}
+
+// Router is how the exchanges are matched against the paths of the specs
+type Router struct {
+	// Type is either gorillamux, the default, or legacy
+	Type                string `yaml:"type"`
+	IgnoreTrailingSlash bool   `yaml:"ignoreTrailingSlash"`
+	CaseInsensitive     bool   `yaml:"caseInsensitive"`
+}
+
+// Rewrite holds the rules applied to the exchanges before routing
+type Rewrite struct {
+	// Hosts replace the hosts of the exchanges, given with or without their port
+	Hosts map[string]string `yaml:"hosts"`
+	Paths []PathRewrite     `yaml:"paths"`
+	// Query lists globs of the query parameters to strip
+	Query   []string       `yaml:"query"`
+	Headers HeadersRewrite `yaml:"headers"`
+}
+
+// PathRewrite replaces the paths matching the regular expression Pattern, Replacement may refer to its groups as $1
+type PathRewrite struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+type HeadersRewrite struct {
+	Set    map[string]string `yaml:"set"`
+	Remove []string          `yaml:"remove"`
+}
+
+// Servers is how the exchanges are matched against the servers of the specs
+type Servers struct {
+	// Mode is either exact, any-host or map
+	Mode string `yaml:"mode"`
+	// Hosts maps the hosts of the exchanges to the URLs they stand for, in the map mode
+	Hosts map[string]string `yaml:"hosts"`
+}
+
+// Spec is a spec along with the exchanges it validates, an exchange must match every binding given
+type Spec struct {
+	Name     string `yaml:"name"`
+	Location string `yaml:"location"`
+	// Host is a glob matched against the host of the exchanges
+	Host string `yaml:"host"`
+	// Prefix is a path prefix, removed before routing when Strip is set
+	Prefix string `yaml:"prefix"`
+	Strip  bool   `yaml:"strip"`
+	// Header is given as "Name: glob", such as "Api-Version: 2*"
+	Header string `yaml:"header"`
+	// Overlays are applied after the ones given as flags
+	Overlays []string `yaml:"overlays"`
+	// Servers overrides the server matching of the configuration
+	Servers *Servers `yaml:"servers"`
+	// candidate is set on the specs given with --candidate-spec
+	candidate bool
 }
 
 type Ignore struct {
@@ -28,3 +134,7 @@ type Ignore struct {
 	Routes    []string `yaml:"routes"`
 	Servers   bool     `yaml:"servers"`
 }
+
+type Ids struct {
+	Template string `yaml:"template"`
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	altsrc "github.com/urfave/cli-altsrc/v3"
 	"github.com/urfave/cli-altsrc/v3/yaml"
@@ -12,13 +13,23 @@ import (
 
 const (
 	specFlagName        = "spec"
+	candidateFlagName   = "candidate-spec"
 	reportFlagName      = "report"
 	formatFlagName      = "format"
 	reportHTMLFlagName  = "report-html"
 	reportJUNITFlagName = "report-junit"
 	reportJSONFlagName  = "report-json"
 	configFileFlagName  = "config-file"
+	environmentFlagName = "environment"
+	specHeaderFlagName  = "spec-header"
+	specCacheFlagName   = "spec-cache"
+	parseCacheFlagName  = "parse-cache"
+	overlayFlagName     = "overlay"
+	exportSpecFlagName  = "export-spec"
 	debugFlagName       = "debug"
+	outputFlagName      = "output"
+	sourceMapFlagName   = "source-map"
+	dereferenceFlagName = "dereference"
 )
 
 func main() {
@@ -29,19 +40,47 @@ func main() {
 		Name:  "static-openapivalidator",
 		Usage: "Check openapi against static results",
 		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    specFlagName,
+				Aliases: []string{"s"},
+				Usage:   "Load openapi specs from `FILES` or URLs, optionally named as name=location",
+				Sources: cli.NewValueSourceChain(yaml.YAML(specFlagName, altsrc.StringSourcer(configFilePath))),
+			},
+			&cli.StringSliceFlag{
+				Name:    candidateFlagName,
+				Usage:   "Also validate against candidate specs from `FILES` or URLs, replacing the specs of the same name, and fail only on regressions",
+				Sources: cli.NewValueSourceChain(yaml.YAML(candidateFlagName, altsrc.StringSourcer(configFilePath))),
+			},
+			&cli.StringSliceFlag{
+				Name:    specHeaderFlagName,
+				Usage:   "Send `HEADER` (\"Name: value\") when loading the spec from a URL",
+				Sources: cli.NewValueSourceChain(yaml.YAML(specHeaderFlagName, altsrc.StringSourcer(configFilePath))),
+			},
+			&cli.StringFlag{
+				Name:    specCacheFlagName,
+				Usage:   "Cache specs loaded from URLs in `DIR` (default: user cache directory)",
+				Sources: cli.NewValueSourceChain(yaml.YAML(specCacheFlagName, altsrc.StringSourcer(configFilePath))),
+			},
+			&cli.StringFlag{
+				Name:    parseCacheFlagName,
+				Usage:   "Cache the loaded and validated specs in `DIR`, until one of their files changes",
+				Sources: cli.NewValueSourceChain(yaml.YAML(parseCacheFlagName, altsrc.StringSourcer(configFilePath))),
+			},
+			&cli.StringSliceFlag{
+				Name:    overlayFlagName,
+				Usage:   "Apply OpenAPI Overlay `FILES` to the specs, in order",
+				Sources: cli.NewValueSourceChain(yaml.YAML(overlayFlagName, altsrc.StringSourcer(configFilePath))),
+			},
 			&cli.StringFlag{
-				Name:     specFlagName,
-				Aliases:  []string{"s"},
-				Usage:    "Load openapi spec from `FILE`",
-				Required: true,
-				Sources:  cli.NewValueSourceChain(yaml.YAML(specFlagName, altsrc.StringSourcer(configFilePath))),
+				Name:    exportSpecFlagName,
+				Usage:   "Export the effective specs, overlays applied, to `DIR`",
+				Sources: cli.NewValueSourceChain(yaml.YAML(exportSpecFlagName, altsrc.StringSourcer(configFilePath))),
 			},
 			&cli.StringSliceFlag{
-				Name:     reportFlagName,
-				Aliases:  []string{"r"},
-				Usage:    "Load report from `FILES`",
-				Required: true,
-				Sources:  cli.NewValueSourceChain(yaml.YAML(reportFlagName, altsrc.StringSourcer(configFilePath))),
+				Name:    reportFlagName,
+				Aliases: []string{"r"},
+				Usage:   "Load report from `FILES`",
+				Sources: cli.NewValueSourceChain(yaml.YAML(reportFlagName, altsrc.StringSourcer(configFilePath))),
 			},
 			&cli.StringFlag{
 				Name:    formatFlagName,
@@ -70,23 +109,113 @@ func main() {
 				Usage:   "Export JSON report to `FILE`",
 				Sources: cli.NewValueSourceChain(yaml.YAML(configFileFlagName, altsrc.StringSourcer(configFilePath))),
 			},
+			&cli.StringFlag{
+				Name:    environmentFlagName,
+				Aliases: []string{"e"},
+				Usage:   "Resolve collection variables from Bruno environment `NAME` or `FILE`, or from Postman environment `FILE`",
+				Sources: cli.NewValueSourceChain(yaml.YAML(environmentFlagName, altsrc.StringSourcer(configFilePath))),
+			},
 			&cli.BoolFlag{
 				Name:    debugFlagName,
 				Usage:   "Enable debug logging",
 				Sources: cli.NewValueSourceChain(yaml.YAML(debugFlagName, altsrc.StringSourcer(configFilePath))),
 			},
 		},
+		Commands: []*cli.Command{
+			{
+				Name:      "bundle",
+				Usage:     "Write the spec given with --spec as a single document, as it is validated",
+				UsageText: "static-openapivalidator --spec FILE bundle [--output FILE] [--dereference] [--source-map FILE]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    outputFlagName,
+						Aliases: []string{"o"},
+						Usage:   "Write the bundled spec to `FILE`, as JSON when it ends with .json (default: standard output)",
+					},
+					&cli.BoolFlag{
+						Name:  dereferenceFlagName,
+						Usage: "Replace every $ref with its target, except circular ones",
+					},
+					&cli.StringFlag{
+						Name:  sourceMapFlagName,
+						Usage: "Write the file and JSON pointer every component comes from to `FILE`",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					specs := cmd.StringSlice(specFlagName)
+					if len(specs) != 1 {
+						return errors.New("bundle takes a single spec")
+					}
+					params := internal.BundleParams{
+						Ctx:           ctx,
+						ApiFilePath:   specs[0],
+						SpecHeaders:   cmd.StringSlice(specHeaderFlagName),
+						SpecCacheDir:  cmd.String(specCacheFlagName),
+						Overlays:      cmd.StringSlice(overlayFlagName),
+						OutputPath:    cmd.String(outputFlagName),
+						SourceMapPath: cmd.String(sourceMapFlagName),
+						Dereference:   cmd.Bool(dereferenceFlagName),
+						Debug:         cmd.Bool(debugFlagName),
+					}
+					return params.Execute()
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "List the changes from a BASE spec to a REVISION, failing on the breaking ones, ranked by recorded exchanges when --report is given",
+				UsageText: "static-openapivalidator [--report FILES --format FORMAT] diff [--output FILE] BASE REVISION",
+				ArgsUsage: "BASE REVISION",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    outputFlagName,
+						Aliases: []string{"o"},
+						Usage:   "Write the changes to `FILE` as JSON",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() != 2 {
+						return errors.New("diff takes a base spec and a revision")
+					}
+					params := internal.DiffParams{
+						Ctx:              ctx,
+						BaseFilePath:     cmd.Args().Get(0),
+						RevisionFilePath: cmd.Args().Get(1),
+						SpecHeaders:      cmd.StringSlice(specHeaderFlagName),
+						SpecCacheDir:     cmd.String(specCacheFlagName),
+						Overlays:         cmd.StringSlice(overlayFlagName),
+						ReportFilePaths:  cmd.StringSlice(reportFlagName),
+						Format:           cmd.String(formatFlagName),
+						Environment:      cmd.String(environmentFlagName),
+						ConfigFilePath:   configFilePath,
+						OutputPath:       cmd.String(outputFlagName),
+						Debug:            cmd.Bool(debugFlagName),
+					}
+					return params.Execute()
+				},
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
+			// Not required by the flag, as the commands do not take reports
+			if len(cmd.StringSlice(reportFlagName)) == 0 {
+				return fmt.Errorf("required flag %q not set", reportFlagName)
+			}
 			params := internal.Params{
-				Ctx:             ctx,
-				ApiFilePath:     cmd.String(specFlagName),
-				ReportFilePaths: cmd.StringSlice(reportFlagName),
-				Format:          cmd.String(formatFlagName),
-				JunitFilePath:   cmd.String(reportJUNITFlagName),
-				HtmlFilePath:    cmd.String(reportHTMLFlagName),
-				JsonFilePath:    cmd.String(reportJSONFlagName),
-				Debug:           cmd.Bool(debugFlagName),
-				ConfigFilePath:  configFilePath,
+				Ctx:                ctx,
+				ApiFilePaths:       cmd.StringSlice(specFlagName),
+				CandidateSpecPaths: cmd.StringSlice(candidateFlagName),
+				SpecHeaders:        cmd.StringSlice(specHeaderFlagName),
+				SpecCacheDir:       cmd.String(specCacheFlagName),
+				ParseCacheDir:      cmd.String(parseCacheFlagName),
+				Overlays:           cmd.StringSlice(overlayFlagName),
+				ExportSpecDir:      cmd.String(exportSpecFlagName),
+				ReportFilePaths:    cmd.StringSlice(reportFlagName),
+				Format:             cmd.String(formatFlagName),
+				JunitFilePath:      cmd.String(reportJUNITFlagName),
+				HtmlFilePath:       cmd.String(reportHTMLFlagName),
+				JsonFilePath:       cmd.String(reportJSONFlagName),
+				Environment:        cmd.String(environmentFlagName),
+				Debug:              cmd.Bool(debugFlagName),
+				ConfigFilePath:     configFilePath,
 			}
 			return params.Execute()
 		},
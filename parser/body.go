@@ -0,0 +1,227 @@
+package test_report
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	bodyKindJSON       = "json"
+	bodyKindUrlencoded = "urlencoded"
+	bodyKindMultipart  = "multipart"
+	bodyKindXML        = "xml"
+	bodyKindText       = "text"
+	bodyKindBinary     = "binary"
+)
+
+// Body is a recorded payload, kept both as the exact bytes that were exchanged
+// and as a human-readable version for the reports
+type Body struct {
+	Raw         []byte
+	Formatted   string
+	ContentType string
+	Kind        string
+	// Encoding describes the Content-Encoding and charset that were removed from the payload
+	Encoding string
+	// codings is the number of content codings removed, the last ones of the Content-Encoding header
+	codings int
+}
+
+// NewBody normalizes a recorded payload according to its Content-Type
+func NewBody(raw []byte, contentType string) Body {
+	body := Body{
+		Raw:         raw,
+		ContentType: contentType,
+		Kind:        textBodyKind(contentType, raw),
+	}
+	if len(raw) == 0 {
+		return body
+	}
+
+	var formatted string
+	var err error
+	switch body.Kind {
+	case bodyKindJSON:
+		formatted, err = formatJSON(raw)
+	case bodyKindUrlencoded:
+		formatted, err = formatUrlencoded(raw)
+	case bodyKindMultipart:
+		formatted, err = formatMultipart(raw, contentType)
+	case bodyKindXML:
+		formatted, err = formatXML(raw)
+	case bodyKindText:
+		formatted = string(raw)
+	default:
+		formatted = formatBinary(raw, contentType)
+	}
+	if err != nil {
+		// Malformed payloads are reported as is, validation will point out the issue
+		formatted = string(raw)
+	}
+	body.Formatted = formatted
+	return body
+}
+
+// Reader returns a reader on the raw payload, or nil when there is none
+func (b Body) Reader() io.Reader {
+	if len(b.Raw) == 0 {
+		return nil
+	}
+	return bytes.NewReader(b.Raw)
+}
+
+// ReadCloser returns a read closer on the raw payload, or nil when there is none
+func (b Body) ReadCloser() io.ReadCloser {
+	if len(b.Raw) == 0 {
+		return nil
+	}
+	return io.NopCloser(bytes.NewReader(b.Raw))
+}
+
+func bodyKind(contentType string, raw []byte) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return bodyKindJSON
+	case mediaType == "application/x-www-form-urlencoded":
+		return bodyKindUrlencoded
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return bodyKindMultipart
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return bodyKindXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return bodyKindText
+	case mediaType == "":
+		// Without any content type, keep printable payloads readable
+		if utf8.Valid(raw) {
+			return bodyKindText
+		}
+	}
+	return bodyKindBinary
+}
+
+// textBodyKind returns the kind of the payload, falling back to binary when a textual payload is not valid text
+func textBodyKind(contentType string, raw []byte) string {
+	kind := bodyKind(contentType, raw)
+	if kind != bodyKindBinary && kind != bodyKindMultipart && !utf8.Valid(raw) {
+		return bodyKindBinary
+	}
+	return kind
+}
+
+func formatJSON(raw []byte) (string, error) {
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, raw, "", "  "); err != nil {
+		return "", err
+	}
+	return prettyJSON.String(), nil
+}
+
+func formatUrlencoded(raw []byte) (string, error) {
+	var lines []string
+	for _, pair := range strings.Split(string(raw), "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		decodedKey, err := url.QueryUnescape(key)
+		if err != nil {
+			return "", err
+		}
+		decodedValue, err := url.QueryUnescape(value)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, decodedKey+": "+decodedValue)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatMultipart(raw []byte, contentType string) (string, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	reader := multipart.NewReader(bytes.NewReader(raw), params["boundary"])
+	var lines []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return "", err
+		}
+		if part.FileName() != "" {
+			lines = append(lines, fmt.Sprintf("%s (%s): %s", part.FormName(), part.FileName(), formatBinary(content, part.Header.Get("Content-Type"))))
+		} else {
+			lines = append(lines, part.FormName()+": "+string(content))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatXML(raw []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+	var prettyXML bytes.Buffer
+	encoder := xml.NewEncoder(&prettyXML)
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		// Indentation is handled by the encoder
+		if charData, ok := token.(xml.CharData); ok && len(bytes.TrimSpace(charData)) == 0 {
+			continue
+		}
+		if err = encoder.EncodeToken(token); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", err
+	}
+	return prettyXML.String(), nil
+}
+
+// binaryPreviewSize is the number of bytes of a binary payload shown in reports
+const binaryPreviewSize = 256
+
+func formatBinary(raw []byte, contentType string) string {
+	if contentType == "" {
+		contentType = "unknown content type"
+	}
+	summary := fmt.Sprintf("<%d bytes of %s>", len(raw), contentType)
+	if len(raw) == 0 {
+		return summary
+	}
+	preview := raw
+	if len(preview) > binaryPreviewSize {
+		preview = preview[:binaryPreviewSize]
+	}
+	summary += "\n" + strings.TrimSuffix(hex.Dump(preview), "\n")
+	if len(preview) < len(raw) {
+		summary += fmt.Sprintf("\n... %d more bytes", len(raw)-len(preview))
+	}
+	return summary
+}
@@ -0,0 +1,104 @@
+package test_report
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"testing"
+)
+
+func TestNewBody(t *testing.T) {
+	scenario := []struct {
+		raw         string
+		contentType string
+		kind        string
+		formatted   string
+	}{
+		{`{"a":1}`, "application/json; charset=utf-8", bodyKindJSON, "{\n  \"a\": 1\n}"},
+		{`{"a":`, "application/problem+json", bodyKindJSON, `{"a":`},
+		{"a=1&b=hello+world&c=%26", "application/x-www-form-urlencoded", bodyKindUrlencoded, "a: 1\nb: hello world\nc: &"},
+		{"<a><b>1</b></a>", "application/xml", bodyKindXML, "<a>\n  <b>1</b>\n</a>"},
+		{"hello", "text/plain", bodyKindText, "hello"},
+		{"hello", "", bodyKindText, "hello"},
+		{"\x00\x01", "application/octet-stream", bodyKindBinary, "<2 bytes of application/octet-stream>\n00000000  00 01                                             |..|"},
+		{"\xff\xfe", "application/json", bodyKindBinary, "<2 bytes of application/json>\n00000000  ff fe                                             |..|"},
+	}
+	for _, elem := range scenario {
+		body := NewBody([]byte(elem.raw), elem.contentType)
+		if body.Kind != elem.kind {
+			t.Fatal(elem.contentType, body.Kind)
+		}
+		if body.Formatted != elem.formatted {
+			t.Fatal(elem.contentType, body.Formatted)
+		}
+		if string(body.Raw) != elem.raw {
+			t.Fatal(elem.contentType, string(body.Raw))
+		}
+	}
+}
+
+func TestBrunoBodyBytes(t *testing.T) {
+	if string(CustomString(`{"a":1}`).Bytes("application/json")) != `{"a":1}` {
+		t.Fatal("object payload should be kept")
+	}
+	if string(CustomString(`"hello"`).Bytes("text/plain")) != "hello" {
+		t.Fatal("text payload should be unquoted")
+	}
+	if string(CustomString(`"hello"`).Bytes("application/json")) != `"hello"` {
+		t.Fatal("JSON string payload should be kept")
+	}
+	if string(CustomString(`"{\"a\":1}"`).Bytes("application/json")) != `{"a":1}` {
+		t.Fatal("serialized JSON payload should be unquoted")
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, _ = writer.Write([]byte{'{', '"', 0xe9, '"', ':', '1', '}'})
+	_ = writer.Close()
+
+	body := DecodeBody(gzipped.Bytes(), "application/json; charset=iso-8859-1", "gzip")
+	if string(body.Raw) != `{"é":1}` {
+		t.Fatal(string(body.Raw))
+	}
+	if body.Encoding != "gzip, charset iso-8859-1" {
+		t.Fatal(body.Encoding)
+	}
+
+	// Already decoded by the tool
+	body = DecodeBody([]byte(`{"a":1}`), "application/json", "gzip")
+	if string(body.Raw) != `{"a":1}` || body.Encoding != "" {
+		t.Fatal(string(body.Raw), body.Encoding)
+	}
+}
+
+func TestStripContentEncoding(t *testing.T) {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, _ = writer.Write([]byte(`{"a":1}`))
+	_ = writer.Close()
+
+	scenario := []struct {
+		raw      []byte
+		header   string
+		expected string
+	}{
+		{gzipped.Bytes(), "gzip", ""},
+		// Only the last coding could be removed, the payload is still br encoded
+		{gzipped.Bytes(), "br, gzip", "br"},
+		// Already decoded by the tool, the header is kept as recorded
+		{[]byte(`{"a":1}`), "gzip", "gzip"},
+		{[]byte(`{"a":1}`), "", ""},
+	}
+	for i, elem := range scenario {
+		header := http.Header{}
+		if elem.header != "" {
+			header.Set("Content-Encoding", elem.header)
+		}
+		stripContentEncoding(header, DecodeBody(elem.raw, "application/json", elem.header))
+		if header.Get("Content-Encoding") != elem.expected {
+			t.Fatal(i, header)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package test_report
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// bruTextBlocks are the blocks of the .bru language holding raw text instead of key/value entries
+var bruTextBlocks = map[string]bool{
+	"body:json":            true,
+	"body:text":            true,
+	"body:xml":             true,
+	"body:sparql":          true,
+	"body:graphql":         true,
+	"body:graphql:vars":    true,
+	"docs":                 true,
+	"script:pre-request":   true,
+	"script:post-response": true,
+	"tests":                true,
+}
+
+// BruFile is a parsed .bru file, either a request or an environment
+type BruFile struct {
+	Blocks []BruBlock
+}
+
+// BruBlock is a top level block of a .bru file
+// Depending on the block, its content is either a dictionary, a text or a list
+type BruBlock struct {
+	Name    string
+	Entries []BruEntry
+	Text    string
+	List    []string
+}
+
+type BruEntry struct {
+	Key      string
+	Value    string
+	Disabled bool
+}
+
+// Block returns the block with the given name, or nil when there is none
+func (f BruFile) Block(name string) *BruBlock {
+	for i := range f.Blocks {
+		if f.Blocks[i].Name == name {
+			return &f.Blocks[i]
+		}
+	}
+	return nil
+}
+
+// Value returns the value of an enabled entry of the block
+func (b *BruBlock) Value(key string) string {
+	if b == nil {
+		return ""
+	}
+	for _, entry := range b.Entries {
+		if entry.Key == key && !entry.Disabled {
+			return entry.Value
+		}
+	}
+	return ""
+}
+
+// entries returns the entries of the block, or none when there is no block
+func (b *BruBlock) entries() []BruEntry {
+	if b == nil {
+		return nil
+	}
+	return b.Entries
+}
+
+// text returns the text of the block, or an empty one when there is no block
+func (b *BruBlock) text() string {
+	if b == nil {
+		return ""
+	}
+	return b.Text
+}
+
+// ParseBru parses the content of a .bru file
+func ParseBru(content []byte) (BruFile, error) {
+	var file BruFile
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	nextLine := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		lineNumber++
+		return strings.TrimRight(scanner.Text(), "\r"), true
+	}
+
+	for {
+		line, ok := nextLine()
+		if !ok {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		var block BruBlock
+		switch {
+		case strings.HasSuffix(trimmed, "{"):
+			block.Name = strings.TrimSpace(strings.TrimSuffix(trimmed, "{"))
+			var lines []string
+			closed := false
+			for !closed {
+				line, ok = nextLine()
+				if !ok {
+					return BruFile{}, fmt.Errorf("line %d: block %s is not closed", lineNumber, block.Name)
+				}
+				if line == "}" || (!bruTextBlocks[block.Name] && strings.TrimSpace(line) == "}") {
+					closed = true
+				} else {
+					lines = append(lines, line)
+				}
+			}
+			if bruTextBlocks[block.Name] {
+				block.Text = dedent(lines)
+			} else {
+				block.Entries = parseBruEntries(lines)
+			}
+		case strings.HasSuffix(trimmed, "["):
+			block.Name = strings.TrimSpace(strings.TrimSuffix(trimmed, "["))
+			for {
+				line, ok = nextLine()
+				if !ok {
+					return BruFile{}, fmt.Errorf("line %d: list %s is not closed", lineNumber, block.Name)
+				}
+				item := strings.TrimSpace(line)
+				if item == "]" {
+					break
+				}
+				if item = strings.TrimSpace(strings.TrimSuffix(item, ",")); item != "" {
+					block.List = append(block.List, item)
+				}
+			}
+		default:
+			return BruFile{}, fmt.Errorf("line %d: unexpected content %q", lineNumber, trimmed)
+		}
+		file.Blocks = append(file.Blocks, block)
+	}
+	return file, scanner.Err()
+}
+
+func parseBruEntries(lines []string) []BruEntry {
+	var entries []BruEntry
+	for i := 0; i < len(lines); i++ {
+		key, value, found := strings.Cut(strings.TrimSpace(lines[i]), ":")
+		if !found {
+			continue
+		}
+		entry := BruEntry{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}
+		if strings.HasPrefix(entry.Key, "~") {
+			entry.Disabled = true
+			entry.Key = strings.TrimPrefix(entry.Key, "~")
+		}
+		if entry.Value == "'''" {
+			// Multiline value, up to the closing quotes
+			var valueLines []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "'''"; i++ {
+				valueLines = append(valueLines, lines[i])
+			}
+			entry.Value = dedent(valueLines)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// dedent removes the indentation shared by every non blank line
+func dedent(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+	for i := range lines {
+		if len(lines[i]) >= indent && indent > 0 {
+			lines[i] = lines[i][indent:]
+		} else if strings.TrimSpace(lines[i]) == "" {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,78 @@
+package test_report
+
+import (
+	"testing"
+)
+
+func TestParseBru(t *testing.T) {
+	bru, err := ParseBru([]byte(`meta {
+  name: get user
+  type: http
+}
+
+get {
+  url: {{baseUrl}}/users/:id?a=b
+}
+
+headers {
+  Accept: application/json
+  ~X-Disabled: 1
+}
+
+body:json {
+  {
+    "a": "}"
+  }
+}
+
+vars:secret [
+  token,
+  password
+]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bru.Block("meta").Value("name") != "get user" {
+		t.Fatal(bru.Block("meta"))
+	}
+	if bru.Block("get").Value("url") != "{{baseUrl}}/users/:id?a=b" {
+		t.Fatal(bru.Block("get"))
+	}
+	headers := bru.Block("headers").Entries
+	if len(headers) != 2 || !headers[1].Disabled || headers[1].Key != "X-Disabled" || bru.Block("headers").Value("X-Disabled") != "" {
+		t.Fatal(headers)
+	}
+	if bru.Block("body:json").Text != "{\n  \"a\": \"}\"\n}" {
+		t.Fatal(bru.Block("body:json").Text)
+	}
+	if secrets := bru.Block("vars:secret").List; len(secrets) != 2 || secrets[1] != "password" {
+		t.Fatal(secrets)
+	}
+	if bru.Block("docs") != nil {
+		t.Fatal("docs should not be found")
+	}
+
+	if _, err = ParseBru([]byte("get {\n  url: x\n")); err == nil {
+		t.Fatal("unclosed block should fail")
+	}
+}
+
+func TestFindBrunoExamples(t *testing.T) {
+	examples, err := findBrunoExamples(&BruBlock{Text: "# Get user\n\n```json 404\n{}\n```\n\n```bash\ncurl x\n```\n\n```application/problem+json 400\n{\"a\": 1}\n```\n\n```200\nok\n```"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 3 {
+		t.Fatal(examples)
+	}
+	if examples[0].Status != 200 || examples[0].ContentType != "text/plain" || examples[0].Body != "ok" {
+		t.Fatal(examples[0])
+	}
+	if examples[1].Status != 400 || examples[1].ContentType != "application/problem+json" {
+		t.Fatal(examples[1])
+	}
+	if examples[2].Status != 404 || examples[2].ContentType != "application/json" {
+		t.Fatal(examples[2])
+	}
+}
@@ -0,0 +1,560 @@
+package test_report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"static-openapivalidator/logger"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/validator"
+	"strconv"
+	"strings"
+)
+
+// BrunoCollectionParser validates the requests defined in Bruno collections, without any run results
+type BrunoCollectionParser struct {
+	// Environment is either the path of an environment file, or the name of an environment of the collections
+	Environment string
+}
+
+// BrunoRequestFile is a request definition, along with the collection context it is sent with
+type BrunoRequestFile struct {
+	Bru        BruFile
+	Filename   string
+	SourceFile string
+	FileOrigin string
+	// Headers and Variables are inherited from the collection and its folders
+	Headers     []BruEntry
+	Variables   map[string]string
+	ProcessEnv  map[string]string
+	Environment string
+}
+
+// BrunoExample is an example response written in the docs of a request
+type BrunoExample struct {
+	Status      int
+	ContentType string
+	Body        string
+}
+
+var brunoMethods = []string{"get", "post", "put", "patch", "delete", "options", "head", "connect", "trace"}
+
+func (p BrunoCollectionParser) Parse(collectionPaths []string, router *routing.Router, config validator.Config) ([]validator.TestResult, error) {
+	var requests []BrunoRequestFile
+
+	for _, path := range collectionPaths {
+		var fileOrigin string
+		if len(collectionPaths) > 1 {
+			fileOrigin = strings.TrimSuffix(filepath.Base(path), ".bru")
+		}
+		found, err := p.readCollection(path)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			found[i].FileOrigin = fileOrigin
+		}
+		requests = append(requests, found...)
+	}
+
+	return translateExchanges(requests, func(request BrunoRequestFile) ([]validator.TestResult, error) {
+		translated, err := brunoRequestFileToOpenAPI(request, router, config)
+		if err != nil {
+			return nil, errors.New(request.SourceFile + ": " + err.Error())
+		}
+		return translated, nil
+	}, config)
+}
+
+// readCollection reads the requests of a collection directory, or a single request file
+func (p BrunoCollectionParser) readCollection(path string) ([]BrunoRequestFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// WalkDir gives cleaned paths, which the root is compared with
+	root := filepath.Clean(path)
+	if !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+
+	variables := make(map[string]string)
+	var headers []BruEntry
+	collection, err := readOptionalBru(filepath.Join(root, "collection.bru"))
+	if err != nil {
+		return nil, err
+	}
+	addBrunoVariables(variables, collection.Block("vars:pre-request"))
+	headers = appendBrunoHeaders(headers, collection.Block("headers"))
+
+	environment, err := p.readEnvironment(root)
+	if err != nil {
+		return nil, err
+	}
+	addBrunoVariables(variables, environment.Block("vars"))
+
+	processEnv, err := readDotEnv(filepath.Join(root, ".env"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if info.IsDir() {
+		err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if file != root && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == "node_modules" ||
+					(entry.Name() == "environments" && filepath.Dir(file) == root)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(file) == ".bru" && entry.Name() != "collection.bru" && entry.Name() != "folder.bru" {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		files = []string{path}
+	}
+
+	var requests []BrunoRequestFile
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		bru, err := ParseBru(content)
+		if err != nil {
+			return nil, errors.New(file + ": " + err.Error())
+		}
+		if brunoMethodBlock(bru) == nil {
+			logger.Log("%s: not validating, no request is defined", file)
+			continue
+		}
+
+		filename, err := filepath.Rel(root, file)
+		if err != nil {
+			return nil, err
+		}
+		request := BrunoRequestFile{
+			Bru:         bru,
+			Filename:    filepath.ToSlash(filename),
+			SourceFile:  file,
+			Headers:     headers,
+			Variables:   make(map[string]string),
+			ProcessEnv:  processEnv,
+			Environment: p.Environment,
+		}
+		for key, value := range variables {
+			request.Variables[key] = value
+		}
+
+		// Folder settings apply to every request below them, the closest folder last
+		if folders := filepath.Dir(filename); folders != "." {
+			dir := root
+			for _, name := range strings.Split(folders, string(filepath.Separator)) {
+				dir = filepath.Join(dir, name)
+				folder, err := readOptionalBru(filepath.Join(dir, "folder.bru"))
+				if err != nil {
+					return nil, err
+				}
+				addBrunoVariables(request.Variables, folder.Block("vars:pre-request"))
+				request.Headers = appendBrunoHeaders(request.Headers, folder.Block("headers"))
+			}
+		}
+		addBrunoVariables(request.Variables, bru.Block("vars:pre-request"))
+
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// readEnvironment reads the chosen environment, given either as a file or by its name in the collection
+func (p BrunoCollectionParser) readEnvironment(root string) (BruFile, error) {
+	if p.Environment == "" {
+		return BruFile{}, nil
+	}
+	path := p.Environment
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(root, "environments", p.Environment+".bru")
+		if _, err = os.Stat(path); err != nil {
+			return BruFile{}, fmt.Errorf("%s: environment %s not found", root, p.Environment)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return BruFile{}, err
+	}
+	environment, err := ParseBru(content)
+	if err != nil {
+		return BruFile{}, errors.New(path + ": " + err.Error())
+	}
+	if secrets := environment.Block("vars:secret"); secrets != nil {
+		// Secret values are not stored in the collection
+		logger.Log("%s: secret variables %s are not resolved", path, strings.Join(secrets.List, ", "))
+	}
+	return environment, nil
+}
+
+// readOptionalBru reads a .bru file that may not exist
+func readOptionalBru(path string) (BruFile, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return BruFile{}, nil
+	}
+	if err != nil {
+		return BruFile{}, err
+	}
+	bru, err := ParseBru(content)
+	if err != nil {
+		return BruFile{}, errors.New(path + ": " + err.Error())
+	}
+	return bru, nil
+}
+
+// readDotEnv reads the .env file of a collection, used for {{process.env.NAME}} variables
+func readDotEnv(path string) (map[string]string, error) {
+	env := make(map[string]string)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return env, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, "'")
+		}
+		env[strings.TrimSpace(key)] = value
+	}
+	return env, scanner.Err()
+}
+
+func addBrunoVariables(variables map[string]string, block *BruBlock) {
+	if block == nil {
+		return
+	}
+	for _, entry := range block.Entries {
+		if !entry.Disabled {
+			variables[entry.Key] = entry.Value
+		}
+	}
+}
+
+func appendBrunoHeaders(headers []BruEntry, block *BruBlock) []BruEntry {
+	if block == nil {
+		return headers
+	}
+	return append(append([]BruEntry{}, headers...), block.Entries...)
+}
+
+func brunoMethodBlock(bru BruFile) *BruBlock {
+	for _, method := range brunoMethods {
+		if block := bru.Block(method); block != nil {
+			return block
+		}
+	}
+	return nil
+}
+
+// resolve replaces the {{variables}} of a value, unknown ones are left as is
+func (r BrunoRequestFile) resolve(value string) string {
+	return postmanVariable.ReplaceAllStringFunc(value, func(match string) string {
+		name := strings.TrimSpace(match[2 : len(match)-2])
+		if envName, isEnv := strings.CutPrefix(name, "process.env."); isEnv {
+			if envValue, ok := r.ProcessEnv[envName]; ok {
+				return envValue
+			}
+			if envValue, ok := os.LookupEnv(envName); ok {
+				return envValue
+			}
+			return match
+		}
+		if variable, ok := r.Variables[name]; ok {
+			return variable
+		}
+		return match
+	})
+}
+
+func brunoRequestFileToOpenAPI(requestFile BrunoRequestFile, router *routing.Router, config validator.Config) ([]validator.TestResult, error) {
+	request, err := translateBrunoRequestFile(requestFile, router, config)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := map[string]string{
+		"source":   requestFile.SourceFile,
+		"filename": requestFile.Filename,
+	}
+	if name := requestFile.Bru.Block("meta").Value("name"); name != "" {
+		infos["name"] = name
+	}
+	if requestFile.Environment != "" {
+		infos["environment"] = requestFile.Environment
+	}
+
+	examples, err := findBrunoExamples(requestFile.Bru.Block("docs"))
+	if err != nil {
+		return nil, err
+	}
+	if len(examples) == 0 {
+		return []validator.TestResult{{
+			AdditionalInfos: infos,
+			Request:         request,
+			Id:              formatBrunoCollectionId(requestFile, "", config.IdTemplate),
+		}}, nil
+	}
+
+	var results []validator.TestResult
+	for _, example := range examples {
+		// Each example gets its own request, as the validation inputs are consumed
+		exampleRequest, err := translateBrunoRequestFile(requestFile, router, config)
+		if err != nil {
+			return nil, err
+		}
+		exampleInfos := map[string]string{"example": strconv.Itoa(example.Status)}
+		for key, value := range infos {
+			exampleInfos[key] = value
+		}
+		results = append(results, validator.TestResult{
+			AdditionalInfos: exampleInfos,
+			Request:         exampleRequest,
+			Response:        translateBrunoExample(example, exampleRequest),
+			Id:              formatBrunoCollectionId(requestFile, strconv.Itoa(example.Status), config.IdTemplate),
+		})
+	}
+	return results, nil
+}
+
+func translateBrunoRequestFile(requestFile BrunoRequestFile, router *routing.Router, config validator.Config) (*validator.TestRequest, error) {
+	methodBlock := brunoMethodBlock(requestFile.Bru)
+
+	headers := http.Header{}
+	for _, header := range append(append([]BruEntry{}, requestFile.Headers...), requestFile.Bru.Block("headers").entries()...) {
+		if !header.Disabled {
+			headers.Set(requestFile.resolve(header.Key), requestFile.resolve(header.Value))
+		}
+	}
+
+	var body Body
+	var parsingError string
+	rawBody, contentType, err := encodePostmanBody(requestFile.postmanBody(methodBlock.Value("body")), headers.Get("Content-Type"))
+	if err != nil {
+		parsingError = "could not rebuild request body: " + err.Error()
+	} else if len(rawBody) > 0 {
+		body = DecodeBody(rawBody, contentType, headers.Get("Content-Encoding"))
+	}
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+	stripContentEncoding(headers, body)
+
+	return newTestRequest(strings.ToUpper(methodBlock.Name), escapeRawUrl(requestFile.url(methodBlock.Value("url"))), headers, body, parsingError, router, config)
+}
+
+// url resolves the variables of the request URL, :path parameters included
+func (r BrunoRequestFile) url(rawUrl string) string {
+	resolved := r.resolve(rawUrl)
+	pathParams := r.Bru.Block("params:path")
+	if pathParams == nil {
+		return resolved
+	}
+
+	base, query, _ := strings.Cut(resolved, "?")
+	scheme, rest, found := strings.Cut(base, "://")
+	if !found {
+		scheme, rest = "", base
+	}
+	segments := strings.Split(rest, "/")
+	for i := 1; i < len(segments); i++ {
+		if name, isParam := strings.CutPrefix(segments[i], ":"); isParam {
+			for _, param := range pathParams.Entries {
+				if param.Key == name && !param.Disabled {
+					segments[i] = r.resolve(param.Value)
+				}
+			}
+		}
+	}
+	resolved = strings.Join(segments, "/")
+	if found {
+		resolved = scheme + "://" + resolved
+	}
+	if query != "" {
+		resolved += "?" + query
+	}
+	return resolved
+}
+
+var brunoFileValue = regexp.MustCompile(`^@file\((.*)\)$`)
+
+// postmanBody maps the body of a request to its Postman equivalent, so that it is encoded the same way
+func (r BrunoRequestFile) postmanBody(mode string) PostmanBody {
+	text := func(block string) string {
+		return r.resolve(r.Bru.Block(block).text())
+	}
+	body := PostmanBody{Mode: "raw"}
+	switch mode {
+	case "", "none":
+		body.Disabled = true
+	case "json", "text", "xml":
+		body.Raw = text("body:" + mode)
+		body.Options.Raw.Language = mode
+	case "sparql":
+		body.Raw = text("body:sparql")
+		body.Options.Raw.Language = "sparql"
+	case "formUrlEncoded":
+		body.Mode = "urlencoded"
+		for _, entry := range r.Bru.Block("body:form-urlencoded").entries() {
+			body.URLEncoded = append(body.URLEncoded, PostmanQueryParam{Key: r.resolve(entry.Key), Value: r.resolve(entry.Value), Disabled: entry.Disabled})
+		}
+	case "multipartForm":
+		body.Mode = "formdata"
+		for _, entry := range r.Bru.Block("body:multipart-form").entries() {
+			param := PostmanFormParam{Key: r.resolve(entry.Key), Type: "text", Disabled: entry.Disabled}
+			if match := brunoFileValue.FindStringSubmatch(entry.Value); match != nil {
+				param.Type = "file"
+				param.Src = strings.Split(match[1], "|")
+			} else {
+				param.Value = r.resolve(entry.Value)
+			}
+			body.FormData = append(body.FormData, param)
+		}
+	case "graphql":
+		body.Mode = "graphql"
+		body.GraphQL = &PostmanGraphQL{Query: text("body:graphql"), Variables: json.RawMessage(text("body:graphql:vars"))}
+	default:
+		body.Mode = mode
+	}
+	return body
+}
+
+// findBrunoExamples reads the example responses of the docs, written as fenced code blocks
+// The info string of the block holds the language or content type of the example, and its status code
+func findBrunoExamples(docs *BruBlock) ([]BrunoExample, error) {
+	if docs == nil {
+		return nil, nil
+	}
+	var examples []BrunoExample
+	var current *BrunoExample
+	var lines []string
+	fence := ""
+	for _, line := range strings.Split(docs.Text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" {
+			if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+				continue
+			}
+			fence = trimmed[:3]
+			current = nil
+			for _, field := range strings.Fields(trimmed[3:]) {
+				if status, err := strconv.Atoi(field); err == nil && status >= 100 && status <= 599 {
+					if current == nil {
+						current = &BrunoExample{}
+					}
+					current.Status = status
+				} else if current == nil || current.ContentType == "" {
+					if current == nil {
+						current = &BrunoExample{}
+					}
+					current.ContentType = field
+				}
+			}
+			if current != nil && current.Status == 0 {
+				// Code blocks without a status are not examples
+				current = nil
+			}
+			lines = nil
+			continue
+		}
+		if trimmed != fence {
+			lines = append(lines, line)
+			continue
+		}
+		fence = ""
+		if current != nil {
+			if contentType, ok := rawLanguageContentTypes[current.ContentType]; ok {
+				current.ContentType = contentType
+			} else if !strings.Contains(current.ContentType, "/") {
+				current.ContentType = "text/plain"
+			}
+			current.Body = dedent(lines)
+			examples = append(examples, *current)
+		}
+	}
+	if fence != "" && current != nil {
+		return nil, fmt.Errorf("example %d in docs is not closed", current.Status)
+	}
+	sort.SliceStable(examples, func(i, j int) bool {
+		return examples[i].Status < examples[j].Status
+	})
+	return examples, nil
+}
+
+func translateBrunoExample(example BrunoExample, request *validator.TestRequest) *validator.TestResponse {
+	headers := http.Header{}
+	headers.Set("Content-Type", example.ContentType)
+	var body Body
+	if example.Body != "" {
+		body = NewBody([]byte(example.Body), example.ContentType)
+	}
+	var parsingError string
+	if request.Route == nil {
+		parsingError = noRouteFound
+	}
+	return &validator.TestResponse{
+		ResponseValidationInput: &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: request.RequestValidationInput,
+			Status:                 example.Status,
+			Header:                 headers,
+			Body:                   body.ReadCloser(),
+		},
+		Body:         body.Formatted,
+		ParsingError: parsingError,
+		Ignored:      request.Ignored,
+	}
+}
+
+const defaultBrunoCollectionIdTemplate = "{file}/{name}/{example}"
+
+// formatBrunoCollectionId formats the id of a request, or of one of its examples
+// The iteration of run reports is empty, so that a template shared with them drops its segment
+func formatBrunoCollectionId(requestFile BrunoRequestFile, example, template string) string {
+	if template == "" {
+		template = defaultBrunoCollectionIdTemplate
+	}
+	return formatIdTemplate(template, map[string]string{
+		"file":      requestFile.FileOrigin,
+		"name":      strings.TrimSuffix(requestFile.Filename, ".bru"),
+		"example":   example,
+		"iteration": "",
+	})
+}
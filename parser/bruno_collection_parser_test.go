@@ -0,0 +1,123 @@
+package test_report
+
+import (
+	"os"
+	"path/filepath"
+	"static-openapivalidator/validator"
+	"testing"
+)
+
+var brunoCollection = map[string]string{
+	"collection.bru": `headers {
+  Accept: application/json
+}
+
+vars:pre-request {
+  userId: 9
+}
+`,
+	"environments/local.bru": `vars {
+  baseUrl: http://localhost:3000/api
+}
+`,
+	"environments/staging.bru": `vars {
+  baseUrl: http://staging.example.com
+}
+`,
+	"users/folder.bru": `headers {
+  X-Folder: users
+}
+
+vars:pre-request {
+  userId: 1
+}
+`,
+	"users/get user.bru": "meta {\n  name: Get user\n}\n\nget {\n  url: {{baseUrl}}/users/{{userId}}\n}\n\ndocs {\n" +
+		"  ```json 200\n  {\"name\": \"john\", \"role\": \"superadmin\"}\n  ```\n\n  ```json 404\n  ```\n}\n",
+	"users/admin/folder.bru": `headers {
+  X-Folder: admin
+}
+
+vars:pre-request {
+  adminId: 2
+}
+`,
+	"users/admin/get admin.bru": `meta {
+  name: Get admin
+}
+
+get {
+  url: {{baseUrl}}/users/{{adminId}}
+}
+`,
+}
+
+// writeBrunoCollection writes the files of a collection to a temporary directory, and returns its path
+func writeBrunoCollection(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestBrunoCollectionParser(t *testing.T) {
+	// Collections given as a relative path, such as -r ., are walked from their cleaned path
+	t.Chdir(writeBrunoCollection(t, brunoCollection))
+	for _, path := range []string{".", "./", "./users/.."} {
+		results, err := BrunoCollectionParser{Environment: "local"}.Parse([]string{path}, newParserRouter(t), validator.Config{})
+		if err != nil {
+			t.Fatal(path, err)
+		}
+
+		expected := map[string]struct {
+			url      string
+			folder   string
+			response string
+		}{
+			// The variables of the closest folder win over the ones of the collection
+			"users/get user/200":    {"http://localhost:3000/api/users/1", "users", validator.Failure},
+			"users/get user/404":    {"http://localhost:3000/api/users/1", "users", validator.Success},
+			"users/admin/get admin": {"http://localhost:3000/api/users/2", "admin", ""},
+		}
+		if len(results) != len(expected) {
+			t.Fatal(path, len(results))
+		}
+		statuses := validationStatuses(t, results)
+		for _, result := range results {
+			elem, ok := expected[result.Id]
+			if !ok {
+				t.Fatal(path, result.Id)
+			}
+			header := result.Request.Request.Header
+			if result.Request.Request.URL.String() != elem.url || header.Get("X-Folder") != elem.folder || header.Get("Accept") != "application/json" {
+				t.Fatal(path, result.Id, result.Request.Request.URL, header)
+			}
+			if statuses[result.Id+" request"] != validator.Success || statuses[result.Id+" response"] != elem.response {
+				t.Fatal(path, result.Id, statuses[result.Id+" request"], statuses[result.Id+" response"])
+			}
+			if result.AdditionalInfos["environment"] != "local" {
+				t.Fatal(path, result.AdditionalInfos)
+			}
+		}
+	}
+}
+
+func TestBrunoCollectionRunTemplate(t *testing.T) {
+	root := writeBrunoCollection(t, brunoCollection)
+	config := validator.Config{IdTemplate: "{file}/{name}/iteration {iteration}"}
+	results, err := BrunoCollectionParser{Environment: filepath.Join(root, "environments", "local.bru")}.Parse([]string{filepath.Join(root, "users", "admin", "get admin.bru")}, newParserRouter(t), config)
+	if err != nil || len(results) != 1 {
+		t.Fatal(err, len(results))
+	}
+	// The iteration of run reports is empty for collections, its segment is dropped
+	if results[0].Id != "get admin" {
+		t.Fatal(results[0].Id)
+	}
+}
@@ -6,90 +6,174 @@ import (
 	"errors"
 	"fmt"
 	"github.com/getkin/kin-openapi/openapi3filter"
-	"github.com/getkin/kin-openapi/routers"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
+	"static-openapivalidator/logger"
+	"static-openapivalidator/routing"
 	"static-openapivalidator/validator"
+	"strconv"
 	"strings"
 )
 
 type BrunoParser struct{}
 
-func (p BrunoParser) Parse(reportFilePaths []string, router routers.Router, config validator.Config) ([]validator.TestResult, error) {
+func (p BrunoParser) Parse(reportFilePaths []string, router *routing.Router, config validator.Config) ([]validator.TestResult, error) {
 	var results []BrunoResult
 
 	for _, path := range reportFilePaths {
-		var reports []BrunoReport
-		reportBytes, err := os.ReadFile(path)
+		reports, err := readBrunoReports(path)
 		if err != nil {
 			return nil, err
 		}
 
-		err = json.Unmarshal(reportBytes, &reports)
-		if err != nil {
-			return nil, errors.New(path + ": " + err.Error())
-		}
-
-		if len(reports) == 0 {
-			return nil, errors.New(path + ": no report in report file")
-		}
-
 		for i := range reports {
+			logger.Log("%s: iteration %d: %d/%d requests passed, %d skipped, %d errored, %d/%d assertions passed, %d/%d tests passed", path,
+				reports[i].IterationIndex+1,
+				reports[i].Summary.PassedRequests, reports[i].Summary.TotalRequests,
+				reports[i].Summary.SkippedRequests, reports[i].Summary.ErrorRequests,
+				reports[i].Summary.PassedAssertions, reports[i].Summary.TotalAssertions,
+				reports[i].Summary.PassedTests, reports[i].Summary.TotalTests)
+
 			for j := range reports[i].Results {
+				result := reports[i].Results[j]
+				if result.Status == brunoStatusSkipped {
+					logger.Log("%s: not validating %s, request was skipped", path, result.Test.Filename)
+					continue
+				}
 				if len(reportFilePaths) > 1 {
-					reports[i].Results[j].FileOrigin = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+					result.FileOrigin = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 				}
-				results = append(results, reports[i].Results[j])
+				result.SourceFile = path
+				result.Iteration = reports[i].IterationIndex + 1
+				result.Iterations = len(reports)
+				results = append(results, result)
 			}
 		}
 	}
 
-	translated, err := translateResults(results, router, config)
+	return translateExchanges(results, func(result BrunoResult) ([]validator.TestResult, error) {
+		translated, err := brunoToOpenAPI(result, router, config)
+		return []validator.TestResult{translated}, err
+	}, config)
+}
+
+// readBrunoReports reads a report file, made of one report per iteration
+func readBrunoReports(path string) ([]BrunoReport, error) {
+	var reports []BrunoReport
+	reportBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return translated, nil
-}
 
-func translateResults(results []BrunoResult, router routers.Router, config validator.Config) ([]validator.TestResult, error) {
-	var final []validator.TestResult
-	for i := range results {
-		res, err := brunoToOpenAPI(results[i], router, config)
-		if err != nil {
-			return nil, err
-		}
-		final = addResultToArray(final, res, config)
+	if trimmed := bytes.TrimSpace(reportBytes); len(trimmed) > 0 && trimmed[0] == '{' {
+		// Single iteration report
+		var report BrunoReport
+		err = json.Unmarshal(reportBytes, &report)
+		reports = []BrunoReport{report}
+	} else {
+		err = json.Unmarshal(reportBytes, &reports)
+	}
+	if err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+
+	if len(reports) == 0 {
+		return nil, errors.New(path + ": no report in report file")
 	}
-	return final, nil
+	return reports, nil
 }
 
-func brunoToOpenAPI(result BrunoResult, router routers.Router, config validator.Config) (validator.TestResult, error) {
+func brunoToOpenAPI(result BrunoResult, router *routing.Router, config validator.Config) (validator.TestResult, error) {
 	request, err := translateRequest(result.Request, router, config)
 	if err != nil {
 		return validator.TestResult{}, err
 	}
-	response, err := translateResponse(result.Response, request)
+	response, err := translateResponse(result.Response, request, brunoTransportError(result))
 	if err != nil {
 		return validator.TestResult{}, err
 	}
 	return validator.TestResult{
-		Request:  request,
-		Response: response,
-		Id:       formatId(result.FileOrigin, result.Test.Filename),
+		AdditionalInfos: brunoAdditionalInfos(result),
+		Assertions:      brunoAssertions(result),
+		Request:         request,
+		Response:        response,
+		Id:              formatId(result, config.IdTemplate),
 	}, nil
 }
 
-func formatId(fileOrigin, filename string) string {
-	filename = strings.TrimSuffix(filename, ".bru")
+func brunoAssertions(result BrunoResult) []validator.Assertion {
+	var final []validator.Assertion
+	for _, outcomes := range [][]BrunoAssertionResult{result.AssertionResults, result.PreRequestTestResults, result.TestResults, result.PostResponseTestResults} {
+		for _, outcome := range outcomes {
+			elem := validator.Assertion{Name: outcome.Name(), Status: validator.Success}
+			switch outcome.Status {
+			case brunoStatusPass:
+			case brunoStatusSkipped:
+				elem.Status = validator.Ignored
+			default:
+				elem.Status = validator.Failure
+				elem.Error = outcome.Error
+			}
+			final = append(final, elem)
+		}
+	}
+	return final
+}
+
+// brunoTransportError returns why no response was received, if so
+// The responses of the requests Bruno marked as errored are never validated, whatever they hold
+func brunoTransportError(result BrunoResult) string {
+	if result.Response.Status != 0 && result.Status != brunoStatusError {
+		return ""
+	}
+	if result.Error != "" {
+		return result.Error
+	}
+	if result.Status == brunoStatusError {
+		return "request errored"
+	}
+	return "no response was received"
+}
+
+// brunoAdditionalInfos gathers what Bruno recorded about the execution, such as its functional outcome
+// The outcomes of the assertions and tests are the Assertions of the result
+func brunoAdditionalInfos(result BrunoResult) map[string]string {
+	infos := map[string]string{
+		"source":    result.SourceFile,
+		"filename":  result.Test.Filename,
+		"iteration": strconv.Itoa(result.Iteration),
+	}
+	if result.Status != "" {
+		infos["testStatus"] = result.Status
+	}
+
+	if result.Response.ResponseTime > 0 {
+		infos["responseTime"] = fmt.Sprintf("%d ms", result.Response.ResponseTime)
+	}
+	return infos
+}
+
+// defaultBrunoIdTemplate only shows the iteration when the report has several of them
+const defaultBrunoIdTemplate = "{file}/{name}/iteration {iteration}"
+
+func formatId(result BrunoResult, template string) string {
+	if template == "" {
+		template = defaultBrunoIdTemplate
+	}
+	filename := strings.TrimSuffix(result.Test.Filename, ".bru")
 	filename = strings.TrimSuffix(filename, "-muted-")
 	filename = strings.TrimSpace(filename)
-	if fileOrigin != "" {
-		filename = fileOrigin + "/" + filename
+
+	var iteration string
+	if result.Iterations > 1 {
+		iteration = strconv.Itoa(result.Iteration)
 	}
-	return filename
+	return formatIdTemplate(template, map[string]string{
+		"file":      result.FileOrigin,
+		"name":      filename,
+		"iteration": iteration,
+	})
 }
 
 func getHeaderValue(header string, headers map[string]any) string {
@@ -101,106 +185,56 @@ func getHeaderValue(header string, headers map[string]any) string {
 	return ""
 }
 
-func translateRequest(brunoRequest BrunoRequest, router routers.Router, config validator.Config) (*validator.TestRequest, error) {
+func translateRequest(brunoRequest BrunoRequest, router *routing.Router, config validator.Config) (*validator.TestRequest, error) {
 	// Translate request
-	var requestBody io.Reader
-	var prettyJSON bytes.Buffer
+	var body Body
 	var parsingError string
 	if brunoRequest.Body != "" {
+		contentType := getHeaderValue("Content-Type", brunoRequest.Headers)
 		// Multipart body
-		if strings.Contains(getHeaderValue("Content-Type", brunoRequest.Headers), "multipart/form-data") {
+		if strings.Contains(contentType, "multipart/form-data") {
 			// Multipart in JSON report has no data as to what was sent, and not event the set fields
 			parsingError = "multipart/form-data is not supported"
 		} else {
-			// JSON Body
-			requestBody = strings.NewReader(string(brunoRequest.Body))
-			if err := json.Indent(&prettyJSON, []byte(brunoRequest.Body), "", "  "); err != nil {
-				return nil, err
-			}
+			body = DecodeBody(brunoRequest.Body.Bytes(contentType), contentType, getHeaderValue("Content-Encoding", brunoRequest.Headers))
 		}
 	}
 
-	// Bruno does not escape URLs
-	splitted := strings.Split(strings.Split(brunoRequest.Url, "?")[0], "/")
-	for i := range splitted {
-		splitted[i] = url.PathEscape(splitted[i])
-	}
-
-	parsedUrl, err := url.Parse(strings.Join(splitted, "/"))
-	if err != nil {
-		return nil, err
-	}
-
-	ignored := false
-	for _, path := range config.IgnoredRoutes {
-		if path.Match(parsedUrl.Path) {
-			ignored = true
-		}
-	}
-
-	httpReq, err := http.NewRequest(brunoRequest.Method, parsedUrl.String(), requestBody)
-	if err != nil {
-		return nil, err
-	}
-	// Set headers
+	headers := http.Header{}
 	for header, value := range brunoRequest.Headers {
-		httpReq.Header.Set(header, fmt.Sprintf("%s", value))
-	}
-
-	route, pathParams, err := router.FindRoute(httpReq)
-	if err != nil {
-		if errors.Is(err, routers.ErrPathNotFound) {
-			parsingError = fmt.Sprintf("could not find route for %s %s: %v", brunoRequest.Method, parsedUrl.String(), err)
-		} else if errors.Is(err, routers.ErrMethodNotAllowed) {
-			parsingError = fmt.Sprintf("bad method for %s %s: %v", brunoRequest.Method, parsedUrl.String(), err)
-		} else {
-			return nil, err
-		}
-	} else {
-		// Disabling security checks
-		route.Spec.Security = nil
-	}
-
-	request := validator.TestRequest{
-		RequestValidationInput: &openapi3filter.RequestValidationInput{
-			Request:    httpReq,
-			PathParams: pathParams,
-			Route:      route,
-		},
-		Body:         prettyJSON.String(),
-		ParsingError: parsingError,
-		Ignored:      ignored,
+		headers.Set(header, fmt.Sprintf("%s", value))
 	}
-
-	return &request, nil
+	stripContentEncoding(headers, body)
+	// Bruno does not always escape URLs, query parameters included
+	return newTestRequest(brunoRequest.Method, escapeRawUrl(brunoRequest.Url), headers, body, parsingError, router, config)
 }
 
-func translateResponse(brunoResponse BrunoResponse, request *validator.TestRequest) (*validator.TestResponse, error) {
+func translateResponse(brunoResponse BrunoResponse, request *validator.TestRequest, transportError string) (*validator.TestResponse, error) {
 	headers := http.Header{}
 	for header, value := range brunoResponse.Headers {
 		headers.Set(header, fmt.Sprintf("%s", value))
 	}
-	var bodyReader io.ReadCloser
-	var prettyJSON bytes.Buffer
+	var body Body
 	if brunoResponse.Body != "" {
-		bodyReader = io.NopCloser(strings.NewReader(string(brunoResponse.Body)))
-		if err := json.Indent(&prettyJSON, []byte(brunoResponse.Body), "", "  "); err != nil {
-			return nil, err
-		}
+		contentType := getHeaderValue("Content-Type", brunoResponse.Headers)
+		body = DecodeBody(brunoResponse.Body.Bytes(contentType), contentType, getHeaderValue("Content-Encoding", brunoResponse.Headers))
 	}
+	stripContentEncoding(headers, body)
 	var parsingError string
 	if request.Route == nil {
-		parsingError = "no route found"
+		parsingError = noRouteFound
 	}
 	return &validator.TestResponse{
 		ResponseValidationInput: &openapi3filter.ResponseValidationInput{
 			RequestValidationInput: request.RequestValidationInput,
-			Status:                 brunoResponse.Status,
+			Status:                 int(brunoResponse.Status),
 			Header:                 headers,
-			Body:                   bodyReader,
+			Body:                   body.ReadCloser(),
 		},
-		Body:         prettyJSON.String(),
-		ParsingError: parsingError,
-		Ignored:      request.Ignored,
+		Body:           body.Formatted,
+		BodyEncoding:   body.Encoding,
+		ParsingError:   parsingError,
+		TransportError: transportError,
+		Ignored:        request.Ignored,
 	}, nil
 }
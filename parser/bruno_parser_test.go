@@ -0,0 +1,279 @@
+package test_report
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/validator"
+	"strings"
+	"testing"
+)
+
+// brunoReport is the output of bru run --reporter-json for two iterations
+// The second request fails the enum of the spec and an assertion, the third one is skipped by its script, the server of the fourth one is down
+const brunoReport = `[
+  {
+    "iterationIndex": 0,
+    "summary": {
+      "totalRequests": 4,
+      "passedRequests": 1,
+      "failedRequests": 1,
+      "skippedRequests": 1,
+      "errorRequests": 1,
+      "totalAssertions": 2,
+      "passedAssertions": 1,
+      "failedAssertions": 1,
+      "totalTests": 1,
+      "passedTests": 1,
+      "failedTests": 0
+    },
+    "results": [
+      {
+        "test": {"filename": "users/Get user.bru"},
+        "request": {"method": "GET", "url": "http://localhost:3000/api/users/1", "headers": {"accept": "application/json"}},
+        "response": {"status": 200, "statusText": "OK", "headers": {"content-type": "application/json; charset=utf-8"}, "data": {"name": "Ada", "role": "admin"}, "responseTime": 12},
+        "error": null,
+        "status": "pass",
+        "assertionResults": [
+          {"uid": "a1", "lhsExpr": "res.status", "rhsExpr": "eq 200", "rhsOperand": "200", "operator": "eq", "status": "pass"}
+        ],
+        "testResults": [
+          {"uid": "t1", "description": "returns the user", "status": "pass"}
+        ],
+        "preRequestTestResults": [],
+        "postResponseTestResults": [],
+        "shouldStopRunnerExecution": false,
+        "runtime": 0.051,
+        "suitename": "users/Get user"
+      },
+      {
+        "test": {"filename": "users/Get admin.bru"},
+        "request": {"method": "GET", "url": "http://localhost:3000/api/users/2", "headers": {"accept": "application/json"}},
+        "response": {"status": 200, "statusText": "OK", "headers": {"content-type": "application/json; charset=utf-8"}, "data": {"name": "Grace", "role": "root"}, "responseTime": 9},
+        "error": null,
+        "status": "fail",
+        "assertionResults": [
+          {"uid": "a2", "lhsExpr": "res.body.role", "rhsExpr": "eq admin", "rhsOperand": "admin", "operator": "eq", "status": "fail", "error": "expected 'root' to equal 'admin'"}
+        ],
+        "testResults": [],
+        "preRequestTestResults": [],
+        "postResponseTestResults": [],
+        "shouldStopRunnerExecution": false,
+        "runtime": 0.032,
+        "suitename": "users/Get admin"
+      },
+      {
+        "test": {"filename": "users/Delete user.bru"},
+        "request": {"method": "DELETE", "url": "http://localhost:3000/api/users/1", "headers": {}},
+        "response": {"status": "skipped", "statusText": "request skipped via pre-request script", "data": null, "responseTime": 0},
+        "error": null,
+        "status": "skipped",
+        "assertionResults": [],
+        "testResults": [],
+        "runtime": 0.001,
+        "suitename": "users/Delete user"
+      },
+      {
+        "test": {"filename": "users/Get other.bru"},
+        "request": {"method": "GET", "url": "http://localhost:3000/api/users/3", "headers": {}},
+        "response": {"status": null, "statusText": null, "headers": null, "data": null, "responseTime": 0},
+        "error": "connect ECONNREFUSED 127.0.0.1:3000",
+        "status": "error",
+        "assertionResults": [],
+        "testResults": [],
+        "runtime": 0.004,
+        "suitename": "users/Get other"
+      }
+    ]
+  },
+  {
+    "iterationIndex": 1,
+    "summary": {
+      "totalRequests": 1,
+      "passedRequests": 1,
+      "failedRequests": 0,
+      "skippedRequests": 0,
+      "errorRequests": 0,
+      "totalAssertions": 1,
+      "passedAssertions": 1,
+      "failedAssertions": 0,
+      "totalTests": 0,
+      "passedTests": 0,
+      "failedTests": 0
+    },
+    "results": [
+      {
+        "test": {"filename": "users/Get user.bru"},
+        "request": {"method": "GET", "url": "http://localhost:3000/api/users/1", "headers": {"accept": "application/json"}},
+        "response": {"status": 200, "statusText": "OK", "headers": {"content-type": "application/json; charset=utf-8"}, "data": {"name": "Ada"}, "responseTime": 7},
+        "error": null,
+        "status": "pass",
+        "assertionResults": [
+          {"uid": "a1", "lhsExpr": "res.status", "rhsExpr": "eq 200", "rhsOperand": "200", "operator": "eq", "status": "pass"}
+        ],
+        "testResults": [],
+        "runtime": 0.02,
+        "suitename": "users/Get user"
+      }
+    ]
+  }
+]`
+
+func TestReadBrunoReports(t *testing.T) {
+	reports, err := readBrunoReports(writeParserFile(t, "bruno.json", brunoReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[1].IterationIndex != 1 {
+		t.Fatal(len(reports), reports[len(reports)-1].IterationIndex)
+	}
+	summary := reports[0].Summary
+	if summary.TotalRequests != 4 || summary.SkippedRequests != 1 || summary.ErrorRequests != 1 || summary.FailedAssertions != 1 || summary.PassedTests != 1 {
+		t.Fatal(summary)
+	}
+	results := reports[0].Results
+	if results[2].Status != brunoStatusSkipped || results[2].Response.Status != 0 {
+		t.Fatal(results[2].Status, results[2].Response.Status)
+	}
+	if results[3].Status != brunoStatusError || results[3].Response.Status != 0 || results[3].Error != "connect ECONNREFUSED 127.0.0.1:3000" {
+		t.Fatal(results[3].Status, results[3].Response.Status, results[3].Error)
+	}
+}
+
+func TestBrunoParser(t *testing.T) {
+	results, err := BrunoParser{}.Parse([]string{writeParserFile(t, "bruno.json", brunoReport)}, newParserRouter(t), validator.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The skipped request is not validated
+	expected := []struct {
+		id         string
+		iteration  string
+		assertions []validator.Assertion
+	}{
+		{"users/Get user/iteration 1", "1", []validator.Assertion{
+			{Name: "res.status: eq 200", Status: validator.Success},
+			{Name: "returns the user", Status: validator.Success},
+		}},
+		{"users/Get admin/iteration 1", "1", []validator.Assertion{
+			{Name: "res.body.role: eq admin", Status: validator.Failure, Error: "expected 'root' to equal 'admin'"},
+		}},
+		{"users/Get other/iteration 1", "1", nil},
+		{"users/Get user/iteration 2", "2", []validator.Assertion{
+			{Name: "res.status: eq 200", Status: validator.Success},
+		}},
+	}
+	if len(results) != len(expected) {
+		t.Fatal(len(results))
+	}
+	for i, elem := range expected {
+		result := results[i]
+		if result.Id != elem.id || result.AdditionalInfos["iteration"] != elem.iteration || len(result.Assertions) != len(elem.assertions) {
+			t.Fatal(i, result.Id, result.AdditionalInfos, result.Assertions)
+		}
+		for j := range elem.assertions {
+			if result.Assertions[j] != elem.assertions[j] {
+				t.Fatal(i, j, result.Assertions[j])
+			}
+		}
+		// The outcomes of the assertions are only kept as assertions
+		for _, info := range []string{"assertions", "tests", "failures"} {
+			if _, found := result.AdditionalInfos[info]; found {
+				t.Fatal(i, info)
+			}
+		}
+	}
+	if results[2].Response.TransportError != "connect ECONNREFUSED 127.0.0.1:3000" || results[1].AdditionalInfos["testStatus"] != "fail" {
+		t.Fatal(results[2].Response.TransportError, results[1].AdditionalInfos)
+	}
+
+	statuses := validationStatuses(t, results)
+	for id, status := range map[string]string{
+		"users/Get user/iteration 1 response":  validator.Success,
+		"users/Get admin/iteration 1 response": validator.Failure,
+		"users/Get other/iteration 1 request":  validator.Success,
+		"users/Get user/iteration 2 response":  validator.Success,
+	} {
+		if statuses[id] != status {
+			t.Fatal(id, statuses[id])
+		}
+	}
+}
+
+func TestBrunoTransportError(t *testing.T) {
+	scenario := []struct {
+		result   BrunoResult
+		expected string
+	}{
+		{BrunoResult{Status: brunoStatusPass, Response: BrunoResponse{Status: 200}}, ""},
+		// The response of an errored request is not validated, even when it has a status
+		{BrunoResult{Status: brunoStatusError, Response: BrunoResponse{Status: 200}, Error: "socket hang up"}, "socket hang up"},
+		{BrunoResult{Status: brunoStatusError}, "request errored"},
+		{BrunoResult{}, "no response was received"},
+	}
+	for i, elem := range scenario {
+		if transportError := brunoTransportError(elem.result); transportError != elem.expected {
+			t.Fatal(i, transportError)
+		}
+	}
+}
+
+const brunoQuerySpec = `openapi: 3.0.3
+info: {title: test, version: "1"}
+servers:
+  - url: http://localhost:3000/api
+paths:
+  /users:
+    get:
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer, maximum: 100}}
+        - {name: name, in: query, schema: {type: string, pattern: "^[a-z ]+$"}}
+      responses:
+        "200": {description: ok}
+`
+
+func TestBrunoQueryValidation(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(brunoQuerySpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenario := []struct {
+		url    string
+		status string
+		error  string
+	}{
+		// Bruno records the query as typed, unescaped
+		{"http://localhost:3000/api/users?limit=10&name=john doe", validator.Success, ""},
+		{"http://localhost:3000/api/users?limit=500", validator.Failure, `parameter "limit" in query`},
+		{"http://localhost:3000/api/users?limit=10&name=John", validator.Failure, `parameter "name" in query`},
+		{"http://localhost:3000/api/users", validator.Failure, `parameter "limit" in query`},
+	}
+	var results []string
+	for _, elem := range scenario {
+		results = append(results, `{"test": {"filename": "Get users.bru"}, "request": {"method": "GET", "url": "`+elem.url+`", "headers": {}},
+      "response": {"status": 200, "headers": {}}, "status": "pass"}`)
+	}
+	report := `{"iterationIndex": 0, "results": [` + strings.Join(results, ",") + `]}`
+
+	parsed, err := BrunoParser{}.Parse([]string{writeParserFile(t, "bruno.json", report)}, routing.NewRouter(routing.Spec{Name: "users", Router: router}), validator.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validated, err := validator.Validate(parsed, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range scenario {
+		request := validated[2*i]
+		if request.GetType() != "request" || request.GetStatus() != elem.status || !strings.Contains(request.GetErrorSummary(), elem.error) {
+			t.Fatal(i, request.GetType(), request.GetStatus(), request.GetErrorSummary())
+		}
+	}
+}
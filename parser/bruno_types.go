@@ -1,14 +1,52 @@
 package test_report
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
 type BrunoReport struct {
-	Results []BrunoResult `json:"results"`
+	IterationIndex int           `json:"iterationIndex"`
+	Summary        BrunoSummary  `json:"summary"`
+	Results        []BrunoResult `json:"results"`
+}
+
+type BrunoSummary struct {
+	TotalRequests    int `json:"totalRequests"`
+	PassedRequests   int `json:"passedRequests"`
+	FailedRequests   int `json:"failedRequests"`
+	SkippedRequests  int `json:"skippedRequests"`
+	ErrorRequests    int `json:"errorRequests"`
+	TotalAssertions  int `json:"totalAssertions"`
+	PassedAssertions int `json:"passedAssertions"`
+	FailedAssertions int `json:"failedAssertions"`
+	TotalTests       int `json:"totalTests"`
+	PassedTests      int `json:"passedTests"`
+	FailedTests      int `json:"failedTests"`
 }
 
+const (
+	brunoStatusPass    = "pass"
+	brunoStatusSkipped = "skipped"
+	brunoStatusError   = "error"
+)
+
 type BrunoResult struct {
 	FileOrigin string
-	Test       BrunoTest     `json:"test"`
-	Request    BrunoRequest  `json:"request"`
-	Response   BrunoResponse `json:"response"`
+	SourceFile string
+	// Iteration is 1 based, out of Iterations in the report
+	Iteration               int
+	Iterations              int
+	Test                    BrunoTest              `json:"test"`
+	Request                 BrunoRequest           `json:"request"`
+	Response                BrunoResponse          `json:"response"`
+	Status                  string                 `json:"status"`
+	Error                   string                 `json:"error"`
+	AssertionResults        []BrunoAssertionResult `json:"assertionResults"`
+	TestResults             []BrunoAssertionResult `json:"testResults"`
+	PreRequestTestResults   []BrunoAssertionResult `json:"preRequestTestResults"`
+	PostResponseTestResults []BrunoAssertionResult `json:"postResponseTestResults"`
 }
 
 type BrunoTest struct {
@@ -23,9 +61,55 @@ type BrunoRequest struct {
 }
 
 type BrunoResponse struct {
-	Status  int            `json:"status"`
-	Headers map[string]any `json:"headers"`
-	Body    CustomString   `json:"data"`
+	Status       BrunoStatus    `json:"status"`
+	StatusText   string         `json:"statusText"`
+	Headers      map[string]any `json:"headers"`
+	Body         CustomString   `json:"data"`
+	ResponseTime int            `json:"responseTime"`
+}
+
+// BrunoAssertionResult is the outcome of either an assertion or a test script
+type BrunoAssertionResult struct {
+	Uid         string `json:"uid"`
+	Description string `json:"description"`
+	LhsExpr     string `json:"lhsExpr"`
+	RhsExpr     string `json:"rhsExpr"`
+	Operator    string `json:"operator"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+}
+
+func (a BrunoAssertionResult) Name() string {
+	if a.Description != "" {
+		return a.Description
+	}
+	return a.LhsExpr + ": " + a.RhsExpr
+}
+
+// BrunoStatus is the HTTP status of a response
+// Bruno stores a string such as "skipped" or null instead when the request was not sent, which is read as 0
+type BrunoStatus int
+
+func (s *BrunoStatus) UnmarshalJSON(data []byte) error {
+	var status any
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+	switch v := status.(type) {
+	case float64:
+		*s = BrunoStatus(v)
+	case string:
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			code = 0
+		}
+		*s = BrunoStatus(code)
+	case nil:
+		*s = 0
+	default:
+		return fmt.Errorf("status of type %T is not supported", v)
+	}
+	return nil
 }
 
 // CustomString unmarshals all the value of the key as string
@@ -33,6 +117,25 @@ type BrunoResponse struct {
 type CustomString string
 
 func (s *CustomString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = ""
+		return nil
+	}
 	*s = CustomString(data)
 	return nil
 }
+
+// Bytes returns the payload as it was exchanged
+// Non JSON payloads are stored by Bruno as JSON strings and need to be unquoted
+func (s CustomString) Bytes(contentType string) []byte {
+	raw := []byte(s)
+	var unquoted string
+	if err := json.Unmarshal(raw, &unquoted); err != nil {
+		return raw
+	}
+	if bodyKind(contentType, raw) == bodyKindJSON && !json.Valid([]byte(unquoted)) {
+		// A JSON string payload
+		return raw
+	}
+	return []byte(unquoted)
+}
@@ -0,0 +1,125 @@
+package test_report
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/htmlindex"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"static-openapivalidator/logger"
+	"strings"
+	"unicode/utf8"
+)
+
+// DecodeBody undoes the Content-Encoding and the charset of a recorded payload before normalizing it
+// Payloads that cannot be decoded, usually because the tool already decoded them, are kept as is
+// The Encoding of the body only lists what was decoded, it is empty when nothing was
+func DecodeBody(raw []byte, contentType, contentEncoding string) Body {
+	var applied []string
+	var codings int
+
+	if len(raw) > 0 {
+		for _, encoding := range contentEncodings(contentEncoding) {
+			decoded, err := decodeContentEncoding(raw, encoding)
+			if err != nil {
+				logger.Log("Body: not decoding %s, left as recorded: %v", encoding, err)
+				break
+			}
+			raw = decoded
+			applied = append(applied, encoding)
+		}
+		codings = len(applied)
+
+		_, params, err := mime.ParseMediaType(contentType)
+		charset := strings.ToLower(params["charset"])
+		// Some tools store the text they already decoded, which must not be transcoded twice
+		alreadyDecoded := utf8.Valid(raw) && !bytes.ContainsRune(raw, 0)
+		if err == nil && charset != "" && charset != "utf-8" && charset != "us-ascii" && !alreadyDecoded {
+			decoded, err := decodeCharset(raw, charset)
+			if err != nil {
+				logger.Log("Body: not decoding charset %s, left as recorded: %v", charset, err)
+			} else {
+				raw = decoded
+				applied = append(applied, "charset "+charset)
+			}
+		}
+	}
+
+	body := NewBody(raw, contentType)
+	body.Encoding = strings.Join(applied, ", ")
+	body.codings = codings
+	return body
+}
+
+// stripContentEncoding removes the codings the body was decoded from from the Content-Encoding header, as the validated payload no longer has them
+// The codings that could not be decoded are kept
+func stripContentEncoding(header http.Header, body Body) {
+	if body.codings == 0 {
+		return
+	}
+	left := contentEncodings(header.Get("Content-Encoding"))
+	left = left[min(body.codings, len(left)):]
+	if len(left) == 0 {
+		header.Del("Content-Encoding")
+		return
+	}
+	slices.Reverse(left)
+	header.Set("Content-Encoding", strings.Join(left, ", "))
+}
+
+// contentEncodings returns the codings of the header in the order they have to be removed
+func contentEncodings(header string) []string {
+	var encodings []string
+	for _, elem := range strings.Split(header, ",") {
+		elem = strings.ToLower(strings.TrimSpace(elem))
+		if elem != "" && elem != "identity" {
+			encodings = append([]string{elem}, encodings...)
+		}
+	}
+	return encodings
+}
+
+func decodeContentEncoding(raw []byte, encoding string) ([]byte, error) {
+	var reader io.Reader
+	var err error
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(raw))
+	case "deflate":
+		// Servers do not agree on whether deflate is zlib wrapped or not
+		reader, err = zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(raw)), nil
+		}
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(raw))
+	case "zstd":
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(bytes.NewReader(raw))
+		if err == nil {
+			defer decoder.Close()
+			reader = decoder
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func decodeCharset(raw []byte, charset string) ([]byte, error) {
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return encoding.NewDecoder().Bytes(raw)
+}
@@ -0,0 +1,160 @@
+package test_report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// multipartBoundary is fixed so that rebuilt bodies are the same from one run to another
+const multipartBoundary = "static-openapivalidator-boundary"
+
+var rawLanguageContentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"text":       "text/plain",
+	"javascript": "application/javascript",
+	"sparql":     "application/sparql-query",
+}
+
+// encodePostmanBody rebuilds the payload sent for a request body, along with its content type
+// The recorded content type is kept unless the payload encoding requires its own
+func encodePostmanBody(body PostmanBody, contentType string) ([]byte, string, error) {
+	if body.Disabled {
+		return nil, contentType, nil
+	}
+
+	switch body.Mode {
+	case "", "raw":
+		if contentType == "" {
+			contentType = rawLanguageContentTypes[body.Options.Raw.Language]
+		}
+		return []byte(body.Raw), contentType, nil
+	case "urlencoded":
+		if contentType == "" {
+			contentType = "application/x-www-form-urlencoded"
+		}
+		return []byte(encodeForm(body.URLEncoded)), contentType, nil
+	case "formdata":
+		return encodePostmanFormData(body.FormData)
+	case "graphql":
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		raw, err := encodePostmanGraphQL(body.GraphQL)
+		return raw, contentType, err
+	case "file":
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		if body.fileUnrecorded() {
+			return nil, contentType, nil
+		}
+		return []byte(body.File.Content), contentType, nil
+	default:
+		return nil, contentType, fmt.Errorf("body mode %s is not supported", body.Mode)
+	}
+}
+
+// fileUnrecorded tells whether the body is a file whose content was not recorded, newman never records it
+// Such a body was still sent, so it is not validated rather than taken as missing
+func (b PostmanBody) fileUnrecorded() bool {
+	return !b.Disabled && b.Mode == "file" && (b.File == nil || b.File.Content == "")
+}
+
+// encodeForm encodes enabled form fields, keeping their order and repeated keys
+func encodeForm(params []PostmanQueryParam) string {
+	var pairs []string
+	for _, param := range params {
+		if !param.Disabled {
+			pairs = append(pairs, url.QueryEscape(param.Key)+"="+url.QueryEscape(param.Value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// encodePostmanFormData rebuilds a multipart payload
+// File contents are not recorded, so file parts only carry their name and content type
+func encodePostmanFormData(params []PostmanFormParam) ([]byte, string, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	if err := writer.SetBoundary(multipartBoundary); err != nil {
+		return nil, "", err
+	}
+
+	for _, param := range params {
+		if param.Disabled {
+			continue
+		}
+		if param.Type != "file" {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(param.Key)))
+			if param.ContentType != "" {
+				header.Set("Content-Type", param.ContentType)
+			}
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err = part.Write([]byte(param.Value)); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		sources := param.Src
+		if len(sources) == 0 {
+			// A file field that was left empty is still sent
+			sources = PostmanSrc{""}
+		}
+		for _, src := range sources {
+			contentType := param.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			header := textproto.MIMEHeader{}
+			// Only the file name is sent, whatever the OS the collection was written on
+			filename := src[strings.LastIndexAny(src, `/\`)+1:]
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(param.Key), escapeQuotes(filename)))
+			header.Set("Content-Type", contentType)
+			if _, err := writer.CreatePart(header); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buffer.Bytes(), writer.FormDataContentType(), nil
+}
+
+func encodePostmanGraphQL(graphQL *PostmanGraphQL) ([]byte, error) {
+	if graphQL == nil {
+		return nil, nil
+	}
+	payload := map[string]any{"query": graphQL.Query}
+
+	variables := graphQL.Variables
+	var variablesAsString string
+	if err := json.Unmarshal(variables, &variablesAsString); err == nil {
+		variables = json.RawMessage(variablesAsString)
+	}
+	if len(bytes.TrimSpace(variables)) > 0 {
+		if !json.Valid(variables) {
+			return nil, errors.New("graphql variables are not valid JSON")
+		}
+		payload["variables"] = variables
+	}
+	return json.Marshal(payload)
+}
+
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
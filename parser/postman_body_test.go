@@ -0,0 +1,68 @@
+package test_report
+
+import (
+	"encoding/json"
+	"static-openapivalidator/validator"
+	"testing"
+)
+
+func TestEncodePostmanBody(t *testing.T) {
+	var body PostmanBody
+	err := json.Unmarshal([]byte(`{
+		"mode": "formdata",
+		"formdata": [
+			{"key": "name", "value": "a b", "type": "text"},
+			{"key": "ignored", "value": "x", "type": "text", "disabled": true},
+			{"key": "avatar", "type": "file", "src": "C:\\images\\me.png", "contentType": "image/png"}
+		]
+	}`), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, contentType, err := encodePostmanBody(body, "multipart/form-data; boundary=--recorded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted := NewBody(raw, contentType).Formatted
+	if formatted != "name: a b\navatar (me.png): <0 bytes of image/png>" {
+		t.Fatal(formatted)
+	}
+
+	raw, contentType, err = encodePostmanBody(PostmanBody{
+		Mode:       "urlencoded",
+		URLEncoded: []PostmanQueryParam{{Key: "a", Value: "1&2"}, {Key: "a", Value: "3"}},
+	}, "")
+	if err != nil || string(raw) != "a=1%262&a=3" || contentType != "application/x-www-form-urlencoded" {
+		t.Fatal(string(raw), contentType, err)
+	}
+
+	raw, contentType, err = encodePostmanBody(PostmanBody{
+		Mode:    "graphql",
+		GraphQL: &PostmanGraphQL{Query: "{ me }", Variables: json.RawMessage(`"{\"id\": 1}"`)},
+	}, "")
+	if err != nil || string(raw) != `{"query":"{ me }","variables":{"id":1}}` || contentType != "application/json" {
+		t.Fatal(string(raw), contentType, err)
+	}
+
+	// newman does not record the content of file bodies
+	raw, contentType, err = encodePostmanBody(PostmanBody{Mode: "file", File: &PostmanFile{Src: "me.png"}}, "image/png")
+	if err != nil || raw != nil || contentType != "image/png" {
+		t.Fatal(string(raw), contentType, err)
+	}
+}
+
+func TestPostmanFileBody(t *testing.T) {
+	request, err := translatePostmanRequest(PostmanRequest{
+		Method: "GET",
+		URL:    PostmanURL{Protocol: "http", Host: []string{"localhost"}, Port: "3000", Path: []string{"api", "users", "1"}},
+		Header: []PostmanHeader{{Key: "Content-Type", Value: "image/png"}},
+		Body:   PostmanBody{Mode: "file", File: &PostmanFile{Src: "/home/me/me.png"}},
+	}, newParserRouter(t), validator.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file was sent, its body is not validated rather than reported missing
+	if request.ParsingError != "" || request.Options == nil || !request.Options.ExcludeRequestBody || request.Body != "<file /home/me/me.png not recorded, not validated>" {
+		t.Fatal(request.ParsingError, request.Options, request.Body)
+	}
+}
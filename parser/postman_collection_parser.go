@@ -0,0 +1,255 @@
+package test_report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/validator"
+	"strings"
+)
+
+// PostmanCollectionParser validates the examples saved in Postman collections, without any run results
+type PostmanCollectionParser struct {
+	// Environment is the path of an exported Postman environment, its values override the collection variables
+	Environment string
+}
+
+// PostmanEnvironment is an exported Postman environment
+type PostmanEnvironment struct {
+	Name   string                    `json:"name"`
+	Values []PostmanEnvironmentValue `json:"values"`
+}
+
+type PostmanEnvironmentValue struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+	// Enabled is true when missing
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// PostmanSavedResponse is an example response saved along with a request of a collection
+// Its body is saved as text, where newman records the responses it receives as a stream
+type PostmanSavedResponse struct {
+	ID     string         `json:"id,omitempty"`
+	Name   string         `json:"name,omitempty"`
+	Code   int            `json:"code,omitempty"`
+	Header PostmanHeaders `json:"header,omitempty"`
+	Body   string         `json:"body,omitempty"`
+	// OriginalRequest is the copy of the request the example was saved for
+	OriginalRequest *PostmanRequest `json:"originalRequest,omitempty"`
+}
+
+// response returns the example as a response recorded by newman
+func (r PostmanSavedResponse) response() PostmanResponse {
+	return PostmanResponse{ID: r.ID, Code: r.Code, Header: r.Header, Stream: PostmanStream(r.Body)}
+}
+
+// PostmanExample is a saved example along with its location in the collection
+type PostmanExample struct {
+	Item       PostmanItem
+	Response   PostmanSavedResponse
+	Request    PostmanRequest
+	Collection string
+	SourceFile string
+	FileOrigin string
+	JsonPath   string
+}
+
+func (p PostmanCollectionParser) Parse(collectionFilePaths []string, router *routing.Router, config validator.Config) ([]validator.TestResult, error) {
+	environment, err := p.readEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	var examples []PostmanExample
+
+	for _, path := range collectionFilePaths {
+		var collection PostmanCollection
+		collectionBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(collectionBytes, &collection)
+		if err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+
+		var fileOrigin string
+		if len(collectionFilePaths) > 1 {
+			fileOrigin = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		variables := make(map[string]string)
+		for _, variable := range collection.Variable {
+			if !variable.Disabled {
+				variables[variable.Key] = variable.String()
+			}
+		}
+		for key, value := range environment {
+			variables[key] = value
+		}
+
+		found, err := findPostmanExamples(collection.Item, "", variables)
+		if err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+		for i := range found {
+			found[i].Collection = collection.Info.Name
+			found[i].SourceFile = path
+			found[i].FileOrigin = fileOrigin
+		}
+		examples = append(examples, found...)
+	}
+
+	return translateExchanges(examples, func(example PostmanExample) ([]validator.TestResult, error) {
+		translated, err := postmanExampleToOpenAPI(example, router, config)
+		return []validator.TestResult{translated}, err
+	}, config)
+}
+
+// readEnvironment reads the enabled values of the chosen environment, if any
+func (p PostmanCollectionParser) readEnvironment() (map[string]string, error) {
+	values := make(map[string]string)
+	if p.Environment == "" {
+		return values, nil
+	}
+	content, err := os.ReadFile(p.Environment)
+	if err != nil {
+		return nil, err
+	}
+	var environment PostmanEnvironment
+	if err = json.Unmarshal(content, &environment); err != nil {
+		return nil, errors.New(p.Environment + ": " + err.Error())
+	}
+	for _, value := range environment.Values {
+		if value.Enabled == nil || *value.Enabled {
+			values[value.Key] = PostmanVariable{Key: value.Key, Value: value.Value}.String()
+		}
+	}
+	return values, nil
+}
+
+// findPostmanExamples walks the collection for saved examples, with their collection variables resolved
+func findPostmanExamples(items []PostmanItem, path string, variables map[string]string) ([]PostmanExample, error) {
+	var examples []PostmanExample
+	for _, item := range items {
+		if len(item.Item) > 0 {
+			folder := item.Name
+			if path != "" {
+				folder = path + "/" + item.Name
+			}
+			found, err := findPostmanExamples(item.Item, folder, variables)
+			if err != nil {
+				return nil, err
+			}
+			examples = append(examples, found...)
+			continue
+		}
+
+		for _, response := range item.Response {
+			// Examples keep a copy of the request they were saved for
+			request := response.OriginalRequest
+			if request == nil {
+				request = item.Request
+			}
+			if request == nil {
+				continue
+			}
+			resolved, err := resolvePostmanVariables(*request, variables)
+			if err != nil {
+				return nil, err
+			}
+			examples = append(examples, PostmanExample{
+				Item:     item,
+				Response: response,
+				Request:  resolved,
+				JsonPath: path,
+			})
+		}
+	}
+	return examples, nil
+}
+
+var postmanVariable = regexp.MustCompile(`{{([^{}]+)}}`)
+
+// resolvePostmanVariables replaces the {{variables}} of a request, unknown ones are left as is
+func resolvePostmanVariables(request PostmanRequest, variables map[string]string) (PostmanRequest, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return PostmanRequest{}, err
+	}
+	resolvedBytes := postmanVariable.ReplaceAllFunc(requestBytes, func(match []byte) []byte {
+		value, ok := variables[strings.TrimSpace(string(match[2:len(match)-2]))]
+		if !ok {
+			return match
+		}
+		// The value is placed inside a JSON string
+		escaped, _ := json.Marshal(value)
+		return escaped[1 : len(escaped)-1]
+	})
+
+	var resolved PostmanRequest
+	err = json.Unmarshal(resolvedBytes, &resolved)
+	return resolved, err
+}
+
+func postmanExampleToOpenAPI(example PostmanExample, router *routing.Router, config validator.Config) (validator.TestResult, error) {
+	request, err := translatePostmanRequest(example.Request, router, config)
+	if err != nil {
+		return validator.TestResult{}, err
+	}
+	response, err := translatePostmanResponse(example.Response.response(), request, "")
+	if err != nil {
+		return validator.TestResult{}, err
+	}
+	if example.Response.Code == 0 && response.ParsingError == "" {
+		response.ParsingError = noExampleStatus
+	}
+
+	infos := map[string]string{
+		"source":  example.SourceFile,
+		"example": example.Response.Name,
+		"itemId":  example.Item.Id,
+	}
+	for key, value := range map[string]string{
+		"collection": example.Collection,
+		"folder":     example.JsonPath,
+	} {
+		if value != "" {
+			infos[key] = value
+		}
+	}
+
+	return validator.TestResult{
+		AdditionalInfos: infos,
+		Request:         request,
+		Response:        response,
+		Id:              formatPostmanExampleId(example, config.IdTemplate),
+	}, nil
+}
+
+// noExampleStatus is the parsing error of the examples saved without a status code, their response cannot be validated
+const noExampleStatus = "example has no status code"
+
+const defaultPostmanExampleIdTemplate = "{file}/{folder}/{name}/{example}"
+
+// formatPostmanExampleId formats the id of an example
+// The placeholders of run reports are empty, so that a template shared with them drops their segments
+func formatPostmanExampleId(example PostmanExample, template string) string {
+	if template == "" {
+		template = defaultPostmanExampleIdTemplate
+	}
+	return formatIdTemplate(template, map[string]string{
+		"file":      example.FileOrigin,
+		"folder":    example.JsonPath,
+		"name":      example.Item.Name,
+		"example":   example.Response.Name,
+		"iteration": "",
+		"position":  "",
+		"ref":       "",
+	})
+}
@@ -1,24 +1,23 @@
 package test_report
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/getkin/kin-openapi/openapi3filter"
-	"github.com/getkin/kin-openapi/routers"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
+	"static-openapivalidator/routing"
 	"static-openapivalidator/validator"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type PostmanParser struct{}
 
-func (p PostmanParser) Parse(reportFilePaths []string, router routers.Router, config validator.Config) ([]validator.TestResult, error) {
+func (p PostmanParser) Parse(reportFilePaths []string, router *routing.Router, config validator.Config) ([]validator.TestResult, error) {
 	var results []PostmanExecution
 
 	for _, path := range reportFilePaths {
@@ -37,6 +36,9 @@ func (p PostmanParser) Parse(reportFilePaths []string, router routers.Router, co
 			if len(reportFilePaths) > 1 {
 				report.Run.Executions[i].FileOrigin = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 			}
+			report.Run.Executions[i].SourceFile = path
+			report.Run.Executions[i].Collection = report.Collection.Info.Name
+			report.Run.Executions[i].Started = report.Run.Timings.Started
 			testId := report.Run.Executions[i].Id
 			// Find the testId in the report path
 
@@ -44,12 +46,10 @@ func (p PostmanParser) Parse(reportFilePaths []string, router routers.Router, co
 			results = append(results, report.Run.Executions[i])
 		}
 	}
-	translated, err := translatePostmanResults(results, router, config)
-	if err != nil {
-		return nil, err
-	}
-
-	return translated, nil
+	return translateExchanges(results, func(result PostmanExecution) ([]validator.TestResult, error) {
+		translated, err := postmanToOpenAPI(result, router, config)
+		return []validator.TestResult{translated}, err
+	}, config)
 }
 
 func findPathToId(id, path string, items []PostmanItem) string {
@@ -68,156 +68,171 @@ func findPathToId(id, path string, items []PostmanItem) string {
 	return ""
 }
 
-func translatePostmanResults(results []PostmanExecution, router routers.Router, config validator.Config) ([]validator.TestResult, error) {
-	var final []validator.TestResult
-	for i := range results {
-		res, err := postmanToOpenAPI(results[i], router, config)
-		if err != nil {
-			return nil, err
-		}
-		final = addResultToArray(final, res, config)
-	}
-	return final, nil
-}
-
-func postmanToOpenAPI(result PostmanExecution, router routers.Router, config validator.Config) (validator.TestResult, error) {
+func postmanToOpenAPI(result PostmanExecution, router *routing.Router, config validator.Config) (validator.TestResult, error) {
 	request, err := translatePostmanRequest(result.Request, router, config)
 	if err != nil {
 		return validator.TestResult{}, err
 	}
-	response, err := translatePostmanResponse(result.Response, request)
+	response, err := translatePostmanResponse(result.Response, request, postmanTransportError(result))
 	if err != nil {
 		return validator.TestResult{}, err
 	}
 	return validator.TestResult{
-		Request:  request,
-		Response: response,
-		Id:       formatPostmanId(result),
+		AdditionalInfos: postmanAdditionalInfos(result),
+		Assertions:      postmanAssertions(result.Assertions),
+		Request:         request,
+		Response:        response,
+		Id:              formatPostmanId(result, config.IdTemplate),
 	}, nil
 }
 
-func formatPostmanId(result PostmanExecution) string {
-	var final []string
-	for _, elem := range []string{result.FileOrigin, result.JsonPath, result.Item.Name} {
-		if elem != "" {
-			final = append(final, elem)
+// postmanAdditionalInfos gathers what newman recorded about the execution
+func postmanAdditionalInfos(result PostmanExecution) map[string]string {
+	infos := map[string]string{
+		"source":    result.SourceFile,
+		"iteration": strconv.Itoa(result.Cursor.Iteration + 1),
+		"itemId":    result.Item.Id,
+	}
+	for key, value := range map[string]string{
+		"collection":   result.Collection,
+		"folder":       result.JsonPath,
+		"executionRef": result.Cursor.Ref,
+	} {
+		if value != "" {
+			infos[key] = value
 		}
 	}
-	return strings.Join(final, "/")
+	if result.Response.ResponseTime > 0 {
+		infos["responseTime"] = fmt.Sprintf("%d ms", result.Response.ResponseTime)
+	}
+	if result.Started > 0 {
+		infos["timestamp"] = time.UnixMilli(result.Started).UTC().Format(time.RFC3339)
+	}
+	return infos
 }
 
-func translatePostmanRequest(postmanRequest PostmanRequest, router routers.Router, config validator.Config) (*validator.TestRequest, error) {
-	// Translate request
-	var requestBody io.Reader
-	var prettyJSON bytes.Buffer
-	var parsingError string
-
-	if postmanRequest.Body.Raw != "" {
-		// Multipart body
-		//if strings.Contains(getHeaderValue("Content-Type", postmanRequest.Header), "multipart/form-data") {
-		//	// Multipart in JSON report has no data as to what was sent, and not event the set fields
-		//	parsingError = "multipart/form-data is not supported"
-		//} else {
-		// JSON Body
-		requestBody = strings.NewReader(postmanRequest.Body.Raw)
-		if err := json.Indent(&prettyJSON, []byte(postmanRequest.Body.Raw), "", "  "); err != nil {
-			return nil, errors.New("could not format request body: " + err.Error())
+func postmanAssertions(assertions []PostmanAssertion) []validator.Assertion {
+	var final []validator.Assertion
+	for _, assertion := range assertions {
+		elem := validator.Assertion{Name: assertion.Assertion, Status: validator.Success}
+		if assertion.Skipped {
+			elem.Status = validator.Ignored
+		} else if assertion.Error != nil {
+			elem.Status = validator.Failure
+			elem.Error = assertion.Error.Message
 		}
+		final = append(final, elem)
 	}
+	return final
+}
 
-	parsedUrl, err := url.Parse(postmanRequest.URL.GetUrl())
-	if err != nil {
-		return nil, err
-	}
-
-	ignored := false
-	for _, path := range config.IgnoredRoutes {
-		if path.Match(parsedUrl.Path) {
-			ignored = true
+// postmanTransportError returns why no response was received, if so
+func postmanTransportError(result PostmanExecution) string {
+	if result.RequestError != nil {
+		if result.RequestError.Code != "" && !strings.Contains(result.RequestError.Message, result.RequestError.Code) {
+			return result.RequestError.Code + ": " + result.RequestError.Message
 		}
+		return result.RequestError.Message
 	}
+	if result.Response.Code == 0 {
+		return "no response was received"
+	}
+	return ""
+}
+
+// defaultPostmanIdTemplate only shows the iteration when the run has several of them
+const defaultPostmanIdTemplate = "{file}/{folder}/{name}/iteration {iteration}"
+
+func formatPostmanId(result PostmanExecution, template string) string {
+	if template == "" {
+		template = defaultPostmanIdTemplate
+	}
+	var iteration string
+	if result.Cursor.Cycles > 1 {
+		iteration = strconv.Itoa(result.Cursor.Iteration + 1)
+	}
+	return formatIdTemplate(template, map[string]string{
+		"file":      result.FileOrigin,
+		"folder":    result.JsonPath,
+		"name":      result.Item.Name,
+		"iteration": iteration,
+		"position":  strconv.Itoa(result.Cursor.Position + 1),
+		"ref":       result.Cursor.Ref,
+	})
+}
+
+func translatePostmanRequest(postmanRequest PostmanRequest, router *routing.Router, config validator.Config) (*validator.TestRequest, error) {
+	// Translate request
+	var body Body
+	var parsingError string
 
-	httpReq, err := http.NewRequest(postmanRequest.Method, parsedUrl.String(), requestBody)
+	rawBody, contentType, err := encodePostmanBody(postmanRequest.Body, getPostmanHeaderValue(postmanRequest.Header, "Content-Type"))
 	if err != nil {
-		return nil, err
+		parsingError = "could not rebuild request body: " + err.Error()
+	} else if len(rawBody) > 0 {
+		body = DecodeBody(rawBody, contentType, getPostmanHeaderValue(postmanRequest.Header, "Content-Encoding"))
 	}
 
-	// Set headers
+	headers := http.Header{}
 	for _, header := range postmanRequest.Header {
-		httpReq.Header.Set(header.Key, header.Value)
-	}
-
-	// TODO: move this out as it should be common
-	route, pathParams, err := router.FindRoute(httpReq)
-	if err != nil {
-		if errors.Is(err, routers.ErrPathNotFound) {
-			parsingError = fmt.Sprintf("could not find route for %s %s: %v", postmanRequest.Method, parsedUrl.String(), err)
-		} else if errors.Is(err, routers.ErrMethodNotAllowed) {
-			parsingError = fmt.Sprintf("bad method for %s %s: %v", postmanRequest.Method, parsedUrl.String(), err)
-		} else {
-			return nil, err
+		if !header.Disabled {
+			headers.Set(header.Key, header.Value)
 		}
-	} else {
-		// Disabling security checks
-		route.Spec.Security = nil
 	}
-
-	request := validator.TestRequest{
-		RequestValidationInput: &openapi3filter.RequestValidationInput{
-			Request:    httpReq,
-			PathParams: pathParams,
-			Route:      route,
-		},
-		Body:         prettyJSON.String(),
-		ParsingError: parsingError,
-		Ignored:      ignored,
+	if contentType != "" {
+		// The rebuilt body may come with its own content type, such as a new multipart boundary
+		headers.Set("Content-Type", contentType)
 	}
+	stripContentEncoding(headers, body)
 
-	return &request, nil
+	request, err := newTestRequest(postmanRequest.Method, postmanRequest.URL.GetUrl(), headers, body, parsingError, router, config)
+	if err == nil && postmanRequest.Body.fileUnrecorded() {
+		request.Options = &openapi3filter.Options{ExcludeRequestBody: true}
+		request.Body = "<file not recorded, not validated>"
+		if postmanRequest.Body.File != nil && postmanRequest.Body.File.Src != "" {
+			request.Body = fmt.Sprintf("<file %s not recorded, not validated>", postmanRequest.Body.File.Src)
+		}
+	}
+	return request, err
 }
 
 func getPostmanHeaderValue(headers []PostmanHeader, headerName string) string {
 	for _, header := range headers {
-		if strings.EqualFold(headerName, header.Key) {
+		if strings.EqualFold(headerName, header.Key) && !header.Disabled {
 			return header.Value
 		}
 	}
 	return ""
 }
 
-func translatePostmanResponse(postmanResponse PostmanResponse, request *validator.TestRequest) (*validator.TestResponse, error) {
+func translatePostmanResponse(postmanResponse PostmanResponse, request *validator.TestRequest, transportError string) (*validator.TestResponse, error) {
 	headers := http.Header{}
 	for _, header := range postmanResponse.Header {
-		headers.Set(header.Key, header.Value)
-	}
-	var bodyReader io.ReadCloser
-	var prettyJSON bytes.Buffer
-	if postmanResponse.Stream != "" {
-		bodyReader = io.NopCloser(strings.NewReader(string(postmanResponse.Stream)))
-
-		contentType := getPostmanHeaderValue(postmanResponse.Header, "Content-Type")
-
-		if strings.Contains(contentType, "json") {
-			if err := json.Indent(&prettyJSON, []byte(postmanResponse.Stream), "", "  "); err != nil {
-				return nil, errors.New("could not format response body: " + err.Error())
-			}
-		} else {
-			prettyJSON.WriteString(string(postmanResponse.Stream))
+		if !header.Disabled {
+			headers.Set(header.Key, header.Value)
 		}
 	}
+	var body Body
+	rawBody := []byte(postmanResponse.Stream)
+	if len(rawBody) > 0 {
+		body = DecodeBody(rawBody, getPostmanHeaderValue(postmanResponse.Header, "Content-Type"), getPostmanHeaderValue(postmanResponse.Header, "Content-Encoding"))
+	}
+	stripContentEncoding(headers, body)
 	var parsingError string
 	if request.Route == nil {
-		parsingError = "no route found"
+		parsingError = noRouteFound
 	}
 	return &validator.TestResponse{
 		ResponseValidationInput: &openapi3filter.ResponseValidationInput{
 			RequestValidationInput: request.RequestValidationInput,
 			Status:                 postmanResponse.Code,
 			Header:                 headers,
-			Body:                   bodyReader,
+			Body:                   body.ReadCloser(),
 		},
-		Body:         prettyJSON.String(),
-		ParsingError: parsingError,
-		Ignored:      request.Ignored,
+		Body:           body.Formatted,
+		BodyEncoding:   body.Encoding,
+		ParsingError:   parsingError,
+		TransportError: transportError,
+		Ignored:        request.Ignored,
 	}, nil
 }
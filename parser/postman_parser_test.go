@@ -1,6 +1,77 @@
 package test_report
 
-import "testing"
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"os"
+	"path/filepath"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/validator"
+	"strings"
+	"testing"
+)
+
+const parserSpec = `openapi: 3.0.3
+info: {title: test, version: "1"}
+servers:
+  - url: http://localhost:3000/api
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name: {type: string}
+                  role: {type: string, enum: [admin, user]}
+        "404": {description: not found}
+`
+
+func newParserRouter(t *testing.T) *routing.Router {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(parserSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routing.NewRouter(routing.Spec{Name: "users", Router: router})
+}
+
+// writeParserFile writes a report or collection to a temporary file, and returns its path
+func writeParserFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// validationStatuses validates parsed results, and returns the status of every request and response by test id
+func validationStatuses(t *testing.T, results []validator.TestResult) map[string]string {
+	validated, err := validator.Validate(results, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]string)
+	for _, result := range validated {
+		statuses[result.GetTestId()+" "+result.GetType()] = result.GetStatus()
+	}
+	return statuses
+}
 
 func TestFindItemId(t *testing.T) {
 	scenario := []PostmanItem{
@@ -42,3 +113,215 @@ func TestFindItemId(t *testing.T) {
 		t.Fatal(result)
 	}
 }
+
+func TestGetUrl(t *testing.T) {
+	scenario := []struct {
+		url      PostmanURL
+		expected string
+	}{
+		{
+			PostmanURL{
+				Protocol: "https",
+				Host:     []string{"api", "example", "com"},
+				Port:     "8443",
+				Path:     []string{"users", ":id", "items"},
+				Variable: []PostmanVariable{{Key: "id", Value: "a b"}},
+				Query: []PostmanQueryParam{
+					{Key: "tag", Value: "x&y"},
+					{Key: "tag", Value: "z"},
+					{Key: "off", Value: "1", Disabled: true},
+					{Key: "q", Value: "already%20encoded"},
+				},
+			},
+			"https://api.example.com:8443/users/a%20b/items?tag=x%26y&tag=z&q=already%20encoded",
+		},
+		{
+			PostmanURL{Host: []string{"http://localhost:3000/api/"}, Port: "8080", Path: []string{"users"}},
+			"http://localhost:3000/api/users",
+		},
+		{
+			PostmanURL{Host: []string{"localhost"}, Port: "3000", Path: []string{"users"}},
+			"http://localhost:3000/users",
+		},
+		{
+			PostmanURL{Raw: "http://localhost:3000/users/1?name=john doe#top"},
+			"http://localhost:3000/users/1?name=john%20doe",
+		},
+	}
+	for _, elem := range scenario {
+		result := elem.url.GetUrl()
+		if result != elem.expected {
+			t.Fatal(result)
+		}
+	}
+}
+
+func TestPostmanStream(t *testing.T) {
+	scenario := map[string]string{
+		`{"type":"Buffer","data":[104,105]}`: "hi",
+		`{"type":"Buffer","data":"aGk="}`:    "hi",
+		`{"type":"Buffer","data":[]}`:        "",
+		`{}`:                                 "",
+		`null`:                               "",
+		`"hi"`:                               "hi",
+		`[104,105]`:                          "hi",
+	}
+	for input, expected := range scenario {
+		var response PostmanResponse
+		err := json.Unmarshal([]byte(`{"stream":`+input+`}`), &response)
+		if err != nil {
+			t.Fatal(input, err)
+		}
+		if string(response.Stream) != expected {
+			t.Fatal(input, string(response.Stream))
+		}
+	}
+}
+
+const postmanCollection = `{
+  "info": {"name": "Users API", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "variable": [{"key": "baseUrl", "value": "http://staging.example.com"}, {"key": "userId", "value": "1"}],
+  "item": [{
+    "name": "Users",
+    "item": [
+      {
+        "name": "Get user",
+        "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/users/{{userId}}", "host": ["{{baseUrl}}"], "path": ["users", "{{userId}}"]}},
+        "response": [
+          {"name": "Found", "code": 200, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "{\"name\": \"john\", \"role\": \"admin\"}"},
+          {"name": "Found", "code": 200, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "{\"name\": \"john\", \"role\": \"superadmin\"}"}
+        ]
+      },
+      {
+        "name": "Admin",
+        "item": [{
+          "name": "Get admin",
+          "request": {"method": "GET", "url": "{{baseUrl}}/users/2"},
+          "response": [{
+            "name": "Not found",
+            "originalRequest": {"method": "GET", "url": {"raw": "{{baseUrl}}/users/:id", "host": ["{{baseUrl}}"], "path": ["users", ":id"], "variable": [{"key": "id", "value": "3"}]}},
+            "code": 404
+          }]
+        }, {
+          "name": "Without examples",
+          "request": {"method": "GET", "url": "{{baseUrl}}/users/4"}
+        }]
+      }
+    ]
+  }]
+}`
+
+const postmanEnvironment = `{
+  "name": "local",
+  "values": [
+    {"key": "baseUrl", "value": "http://localhost:3000/api", "enabled": true},
+    {"key": "userId", "value": "9", "enabled": false}
+  ]
+}`
+
+func TestPostmanCollectionParser(t *testing.T) {
+	parser := PostmanCollectionParser{Environment: writeParserFile(t, "local.postman_environment.json", postmanEnvironment)}
+	results, err := parser.Parse([]string{writeParserFile(t, "users.json", postmanCollection)}, newParserRouter(t), validator.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		id       string
+		url      string
+		request  string
+		response string
+	}{
+		{"Users/Get user/Found", "http://localhost:3000/api/users/1", validator.Success, validator.Success},
+		// Contradicts the enum of the spec
+		{"Users/Get user/Found (2)", "http://localhost:3000/api/users/1", validator.Success, validator.Failure},
+		// Validated with the request saved along the example
+		{"Users/Admin/Get admin/Not found", "http://localhost:3000/api/users/3", validator.Success, validator.Success},
+	}
+	if len(results) != len(expected) {
+		t.Fatal(len(results))
+	}
+	statuses := validationStatuses(t, results)
+	for i, elem := range expected {
+		if results[i].Id != elem.id || results[i].Request.Request.URL.String() != elem.url {
+			t.Fatal(i, results[i].Id, results[i].Request.Request.URL)
+		}
+		if statuses[elem.id+" request"] != elem.request || statuses[elem.id+" response"] != elem.response {
+			t.Fatal(elem.id, statuses[elem.id+" request"], statuses[elem.id+" response"])
+		}
+	}
+	if results[0].AdditionalInfos["collection"] != "Users API" || results[2].AdditionalInfos["folder"] != "Users/Admin" {
+		t.Fatal(results[0].AdditionalInfos, results[2].AdditionalInfos)
+	}
+}
+
+func TestPostmanCollectionParserInvalidUrl(t *testing.T) {
+	// Without the environment, {{baseUrl}} is unresolved and the URL cannot be parsed
+	collection := writeParserFile(t, "users.json", postmanCollection)
+	variables := `"variable": [{"key": "baseUrl", "value": "http://staging.example.com"}, {"key": "userId", "value": "1"}],`
+	content, _ := os.ReadFile(collection)
+	if err := os.WriteFile(collection, []byte(strings.Replace(string(content), variables, "", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := PostmanCollectionParser{}.Parse([]string{collection}, newParserRouter(t), validator.Config{})
+	if err != nil || len(results) != 3 {
+		t.Fatal(err, len(results))
+	}
+	statuses := validationStatuses(t, results)
+	for _, result := range results {
+		if statuses[result.Id+" request"] != validator.Warning || !strings.HasPrefix(result.Request.ParsingError, invalidUrl) {
+			t.Fatal(result.Id, statuses[result.Id+" request"], result.Request.ParsingError)
+		}
+	}
+}
+
+func TestPostmanEncodedResponse(t *testing.T) {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, _ = writer.Write([]byte(`{"name": "Ada"}`))
+	_ = writer.Close()
+	report := `{
+  "collection": {"info": {"name": "Users API"}, "item": [{"id": "i1", "name": "Get user"}]},
+  "run": {"executions": [{
+    "id": "i1",
+    "item": {"id": "i1", "name": "Get user"},
+    "request": {"method": "GET", "url": "http://localhost:3000/api/users/1", "header": []},
+    "response": {"code": 200, "header": [{"key": "Content-Type", "value": "application/json"}, {"key": "Content-Encoding", "value": "gzip"}],
+      "stream": {"type": "Buffer", "data": "` + base64.StdEncoding.EncodeToString(gzipped.Bytes()) + `"}}
+  }]}
+}`
+
+	results, err := PostmanParser{}.Parse([]string{writeParserFile(t, "newman.json", report)}, newParserRouter(t), validator.Config{})
+	if err != nil || len(results) != 1 {
+		t.Fatal(err, len(results))
+	}
+	// The validated headers describe the decoded payload, the recorded encoding is kept along with the result
+	if encoding := results[0].Response.Header.Get("Content-Encoding"); encoding != "" {
+		t.Fatal(encoding)
+	}
+	if results[0].AdditionalInfos["responseEncoding"] != "gzip" {
+		t.Fatal(results[0].AdditionalInfos)
+	}
+	if statuses := validationStatuses(t, results); statuses["Get user response"] != validator.Success {
+		t.Fatal(statuses)
+	}
+}
+
+func TestPostmanCollectionParserRunTemplate(t *testing.T) {
+	// The example saved without a status code cannot be validated
+	collection := writeParserFile(t, "users.json", strings.Replace(postmanCollection, `"code": 404`, `"status": "Not Found"`, 1))
+	config := validator.Config{IdTemplate: "{file}/{folder}/{name}/iteration {iteration}"}
+	parser := PostmanCollectionParser{Environment: writeParserFile(t, "local.postman_environment.json", postmanEnvironment)}
+	results, err := parser.Parse([]string{collection}, newParserRouter(t), config)
+	if err != nil || len(results) != 3 {
+		t.Fatal(err, len(results))
+	}
+	// The iteration of run reports is empty for examples, its segment is dropped
+	if results[0].Id != "Users/Get user" || results[2].Id != "Users/Admin/Get admin" {
+		t.Fatal(results[0].Id, results[2].Id)
+	}
+	statuses := validationStatuses(t, results)
+	if statuses["Users/Admin/Get admin response"] != validator.Warning || results[2].Response.ParsingError != noExampleStatus {
+		t.Fatal(statuses["Users/Admin/Get admin response"], results[2].Response.ParsingError)
+	}
+}
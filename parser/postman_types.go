@@ -1,8 +1,11 @@
 package test_report
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -12,91 +15,270 @@ type PostmanReport struct {
 }
 
 type PostmanCollection struct {
-	Item []PostmanItem `json:"item"`
+	Info     PostmanInfo       `json:"info"`
+	Item     []PostmanItem     `json:"item"`
+	Variable []PostmanVariable `json:"variable,omitempty"`
+}
+
+type PostmanInfo struct {
+	Name string `json:"name"`
 }
 
 type PostmanRun struct {
 	Executions []PostmanExecution `json:"executions"`
+	Timings    PostmanTimings     `json:"timings"`
+}
+
+// PostmanTimings are unix timestamps in milliseconds
+type PostmanTimings struct {
+	Started   int64 `json:"started"`
+	Completed int64 `json:"completed"`
 }
 
 type PostmanExecution struct {
-	Item       PostmanItem     `json:"item,omitempty"`
-	Request    PostmanRequest  `json:"request,omitempty"`
-	Response   PostmanResponse `json:"response,omitempty"`
-	Id         string          `json:"id,omitempty"`
-	FileOrigin string
-	JsonPath   string
+	Item     PostmanItem     `json:"item,omitempty"`
+	Request  PostmanRequest  `json:"request,omitempty"`
+	Response PostmanResponse `json:"response,omitempty"`
+	Id       string          `json:"id,omitempty"`
+	Cursor   PostmanCursor   `json:"cursor,omitempty"`
+	// RequestError is set by newman when the request could not be sent or no response was received
+	RequestError *PostmanRequestError `json:"requestError,omitempty"`
+	Assertions   []PostmanAssertion   `json:"assertions,omitempty"`
+	FileOrigin   string
+	SourceFile   string
+	Collection   string
+	Started      int64
+	JsonPath     string
+}
+
+type PostmanAssertion struct {
+	Assertion string                 `json:"assertion"`
+	Skipped   bool                   `json:"skipped,omitempty"`
+	Error     *PostmanAssertionError `json:"error,omitempty"`
+}
+
+type PostmanAssertionError struct {
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type PostmanRequestError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// PostmanCursor locates an execution in a run, iterations and positions are 0 based
+type PostmanCursor struct {
+	Iteration int    `json:"iteration"`
+	Position  int    `json:"position"`
+	Length    int    `json:"length"`
+	Cycles    int    `json:"cycles"`
+	Ref       string `json:"ref,omitempty"`
 }
 
 type PostmanItem struct {
-	Name string        `json:"name,omitempty"`
-	Id   string        `json:"id"`
-	Item []PostmanItem `json:"item"`
+	Name    string          `json:"name,omitempty"`
+	Id      string          `json:"id"`
+	Item    []PostmanItem   `json:"item"`
+	Request *PostmanRequest `json:"request,omitempty"`
+	// Response holds the examples saved along with the request
+	Response []PostmanSavedResponse `json:"response,omitempty"`
 }
 
 type PostmanURL struct {
+	Raw      string              `json:"raw,omitempty"`
 	Protocol string              `json:"protocol,omitempty"`
 	Port     string              `json:"port,omitempty"`
 	Path     []string            `json:"path,omitempty"`
 	Host     []string            `json:"host,omitempty"`
 	Query    []PostmanQueryParam `json:"query,omitempty"`
-	Variable []any               `json:"variable,omitempty"`
+	Variable []PostmanVariable   `json:"variable,omitempty"`
+}
+
+// UnmarshalJSON also accepts URLs given as a plain string, as collections allow it
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = PostmanURL{Raw: raw}
+		return nil
+	}
+	type postmanURL PostmanURL
+	return json.Unmarshal(data, (*postmanURL)(u))
 }
 
+// GetUrl rebuilds the URL that was called, with path variables substituted and components escaped
 func (u PostmanURL) GetUrl() string {
+	if len(u.Host) == 0 {
+		// Nothing was parsed by postman, only the raw URL can be used
+		return escapeRawUrl(u.Raw)
+	}
+
 	var sb strings.Builder
 
-	if u.Protocol != "" {
-		sb.WriteString(u.Protocol + "://")
-	}
-	sb.WriteString(u.Host[0])
-
-	if u.Port != "" {
-		sb.WriteString(":" + u.Port)
-	}
-	sb.WriteString("/")
-	sb.WriteString(strings.Join(u.Path, "/"))
-
-	if len(u.Query) > 0 {
-		start := true
-		for _, elem := range u.Query {
-			if !elem.Disabled {
-				if start {
-					sb.WriteString("?")
-					start = false
-				} else {
-					sb.WriteString("&")
-				}
-				sb.WriteString(elem.Key)
-				sb.WriteString("=")
-				sb.WriteString(elem.Value)
+	protocol := u.Protocol
+	if protocol == "" {
+		protocol = "http"
+	}
+	host := strings.Join(u.Host, ".")
+	if base, err := url.Parse(host); err == nil && base.Scheme != "" && base.Host != "" {
+		// The host comes from a variable holding a whole base URL, such as http://localhost:3000/api
+		sb.WriteString(base.Scheme + "://" + base.Host + strings.TrimSuffix(base.EscapedPath(), "/"))
+	} else {
+		sb.WriteString(protocol + "://" + host)
+		if u.Port != "" {
+			sb.WriteString(":" + u.Port)
+		}
+	}
+
+	for _, segment := range u.Path {
+		sb.WriteString("/")
+		if name, isVariable := strings.CutPrefix(segment, ":"); isVariable {
+			if value, ok := u.variableValue(name); ok {
+				segment = value
 			}
 		}
+		sb.WriteString(escapePathSegment(segment))
+	}
+
+	var query []string
+	for _, elem := range u.Query {
+		if !elem.Disabled {
+			// Repeated keys are kept as is for exploded array parameters
+			query = append(query, escapeQueryComponent(elem.Key)+"="+escapeQueryComponent(elem.Value))
+		}
+	}
+	if len(query) > 0 {
+		sb.WriteString("?" + strings.Join(query, "&"))
 	}
 
 	return sb.String()
 }
 
+func (u PostmanURL) variableValue(name string) (string, bool) {
+	for _, variable := range u.Variable {
+		if variable.Key == name && !variable.Disabled {
+			return variable.String(), true
+		}
+	}
+	return "", false
+}
+
 type PostmanQueryParam struct {
 	Disabled bool   `json:"disabled"`
 	Key      string `json:"key"`
 	Value    string `json:"value"`
 }
 
+type PostmanVariable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+func (v PostmanVariable) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(v.Value)
+}
+
 type PostmanHeader struct {
-	Key   string `json:"key,omitempty"`
-	Value string `json:"value,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// PostmanHeaders also accepts headers given as a raw string, as collections allow it
+type PostmanHeaders []PostmanHeader
+
+func (p *PostmanHeaders) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*p = nil
+		for _, line := range strings.Split(raw, "\n") {
+			if key, value, found := strings.Cut(line, ":"); found {
+				*p = append(*p, PostmanHeader{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+			}
+		}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]PostmanHeader)(p))
 }
 
 type PostmanRequest struct {
-	URL    PostmanURL      `json:"url,omitempty"`
-	Header []PostmanHeader `json:"header,omitempty"`
-	Body   PostmanBody     `json:"body,omitempty"`
-	Method string          `json:"method,omitempty"`
+	URL    PostmanURL     `json:"url,omitempty"`
+	Header PostmanHeaders `json:"header,omitempty"`
+	Body   PostmanBody    `json:"body,omitempty"`
+	Method string         `json:"method,omitempty"`
+}
+
+// UnmarshalJSON also accepts requests given as a plain URL, as collections allow it
+func (r *PostmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*r = PostmanRequest{Method: http.MethodGet, URL: PostmanURL{Raw: raw}}
+		return nil
+	}
+	type postmanRequest PostmanRequest
+	return json.Unmarshal(data, (*postmanRequest)(r))
 }
 
 type PostmanBody struct {
-	Raw string `json:"raw"`
+	Mode       string              `json:"mode,omitempty"`
+	Raw        string              `json:"raw,omitempty"`
+	URLEncoded []PostmanQueryParam `json:"urlencoded,omitempty"`
+	FormData   []PostmanFormParam  `json:"formdata,omitempty"`
+	File       *PostmanFile        `json:"file,omitempty"`
+	GraphQL    *PostmanGraphQL     `json:"graphql,omitempty"`
+	Options    PostmanBodyOptions  `json:"options,omitempty"`
+	Disabled   bool                `json:"disabled,omitempty"`
+}
+
+type PostmanFormParam struct {
+	Key         string     `json:"key"`
+	Value       string     `json:"value,omitempty"`
+	Type        string     `json:"type,omitempty"`
+	Src         PostmanSrc `json:"src,omitempty"`
+	ContentType string     `json:"contentType,omitempty"`
+	Disabled    bool       `json:"disabled,omitempty"`
+}
+
+// PostmanSrc holds the paths of the files of a form field, given either as a single path or as a list
+type PostmanSrc []string
+
+func (p *PostmanSrc) UnmarshalJSON(data []byte) error {
+	var src any
+	if err := json.Unmarshal(data, &src); err != nil {
+		return err
+	}
+	switch v := src.(type) {
+	case string:
+		*p = PostmanSrc{v}
+	case []any:
+		for _, elem := range v {
+			if path, ok := elem.(string); ok {
+				*p = append(*p, path)
+			}
+		}
+	}
+	return nil
+}
+
+type PostmanFile struct {
+	Src     string `json:"src,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type PostmanGraphQL struct {
+	Query string `json:"query,omitempty"`
+	// Variables are usually exported as a JSON string, but can also be an object
+	Variables json.RawMessage `json:"variables,omitempty"`
+}
+
+type PostmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language,omitempty"`
+	} `json:"raw,omitempty"`
 }
 
 type Header struct {
@@ -104,30 +286,73 @@ type Header struct {
 	Value string `json:"value,omitempty"`
 }
 
-type PostmanStream string
+// PostmanStream holds the bytes of a response, as newman recorded them
+type PostmanStream []byte
 
 func (p *PostmanStream) UnmarshalJSON(data []byte) error {
-	var jsonStruct postmanStreamJson
-	err := json.Unmarshal(data, &jsonStruct)
-	if err != nil {
+	var stream any
+	if err := json.Unmarshal(data, &stream); err != nil {
 		return err
 	}
-	if jsonStruct.Type != "Buffer" {
-		return fmt.Errorf("%s stream is not supported", jsonStruct.Type)
+	switch v := stream.(type) {
+	case nil:
+		// No response body
+		*p = nil
+	case string:
+		// Body exported as text
+		*p = PostmanStream(v)
+	case []any:
+		// Bytes exported without the Buffer wrapper
+		return (*postmanBufferData)(p).UnmarshalJSON(data)
+	case map[string]any:
+		var jsonStruct postmanStreamJson
+		if err := json.Unmarshal(data, &jsonStruct); err != nil {
+			return err
+		}
+		if jsonStruct.Type != "" && jsonStruct.Type != "Buffer" {
+			return fmt.Errorf("%s stream is not supported", jsonStruct.Type)
+		}
+		*p = PostmanStream(jsonStruct.Data)
+	default:
+		return fmt.Errorf("stream of type %T is not supported", v)
 	}
-	*p = PostmanStream(jsonStruct.Data)
 	return nil
 }
 
 type postmanStreamJson struct {
-	Type string `json:"type,omitempty"`
-	Data []byte `json:"data,omitempty"`
+	Type string            `json:"type,omitempty"`
+	Data postmanBufferData `json:"data,omitempty"`
+}
+
+// postmanBufferData is the content of a serialized node Buffer, either a list of bytes or a base64 string
+type postmanBufferData []byte
+
+func (p *postmanBufferData) UnmarshalJSON(data []byte) error {
+	var asString string
+	if err := json.Unmarshal(data, &asString); err == nil {
+		decoded, err := base64.StdEncoding.DecodeString(asString)
+		if err != nil {
+			return err
+		}
+		*p = decoded
+		return nil
+	}
+	var asBytes []int
+	if err := json.Unmarshal(data, &asBytes); err != nil {
+		return err
+	}
+	*p = make([]byte, len(asBytes))
+	for i := range asBytes {
+		(*p)[i] = byte(asBytes[i])
+	}
+	return nil
 }
 
 type PostmanResponse struct {
-	ID           string          `json:"id,omitempty"`
-	Code         int             `json:"code,omitempty"`
-	Header       []PostmanHeader `json:"header,omitempty"`
-	Stream       PostmanStream   `json:"stream,omitempty"`
-	ResponseSize int             `json:"responseSize,omitempty"`
+	ID           string         `json:"id,omitempty"`
+	Code         int            `json:"code,omitempty"`
+	Header       PostmanHeaders `json:"header,omitempty"`
+	Stream       PostmanStream  `json:"stream,omitempty"`
+	ResponseSize int            `json:"responseSize,omitempty"`
+	ResponseTime int            `json:"responseTime,omitempty"`
 }
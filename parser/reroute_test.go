@@ -0,0 +1,103 @@
+package test_report
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"net/http"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/validator"
+	"strconv"
+	"testing"
+)
+
+func newRerouteRouter(t *testing.T, path string, maxLength int) *routing.Router {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.0.3
+info: {title: test, version: "1"}
+paths:
+  ` + path + `:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {type: object, properties: {name: {type: string, maxLength: ` + strconv.Itoa(maxLength) + `}}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {type: object, required: [id]}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routing.NewRouter(routing.Spec{Name: "users", Router: router})
+}
+
+func TestReroute(t *testing.T) {
+	current := newRerouteRouter(t, "/users", 9)
+	scenario := []struct {
+		candidate  *routing.Router
+		transition string
+	}{
+		{newRerouteRouter(t, "/users", 9), ""},
+		{newRerouteRouter(t, "/users", 3), validator.Regressed},
+		{newRerouteRouter(t, "/people", 9), validator.NewlyUnmatched},
+	}
+	for i, elem := range scenario {
+		body := Body{Raw: []byte(`{"name":"john"}`), Formatted: `{"name":"john"}`}
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		request, err := newTestRequest(http.MethodPost, "http://localhost/users", header, body, "", current, validator.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		responseBody := Body{Raw: []byte(`{"id":1}`), Formatted: `{"id":1}`}
+		response := &validator.TestResponse{
+			ResponseValidationInput: &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: request.RequestValidationInput,
+				Status:                 http.StatusOK,
+				Header:                 header,
+				Body:                   responseBody.ReadCloser(),
+			},
+			Body: responseBody.Formatted,
+		}
+		results := []validator.TestResult{{Id: "create user", Request: request, Response: response}}
+
+		rerouted, err := Reroute(results, elem.candidate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validated, err := validator.Validate(results, context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		candidateValidated, err := validator.Validate(rerouted, context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = validator.Compare(validated, candidateValidated); err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range validated {
+			if result.GetStatus() != validator.Success {
+				t.Fatal(i, result.GetType(), result.GetErrorSummary())
+			}
+		}
+		if transition := validated[0].GetCandidate().Transition; transition != elem.transition {
+			t.Fatal(i, "request", transition, validated[0].GetCandidate().ErrorSummary)
+		}
+		// The response passes against both specs, unless it has no route
+		expected := ""
+		if elem.transition == validator.NewlyUnmatched {
+			expected = validator.NewlyUnmatched
+		}
+		if transition := validated[1].GetCandidate().Transition; transition != expected {
+			t.Fatal(i, "response", transition, validated[1].GetCandidate().ErrorSummary)
+		}
+	}
+}
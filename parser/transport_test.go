@@ -0,0 +1,96 @@
+package test_report
+
+import (
+	"context"
+	"static-openapivalidator/reports"
+	"static-openapivalidator/validator"
+	"testing"
+)
+
+// newmanReport is a newman run whose second request got no response, the server being down
+const newmanReport = `{
+  "collection": {"info": {"name": "Users API"}, "item": [{"id": "i1", "name": "Get user"}, {"id": "i2", "name": "Get other"}]},
+  "run": {
+    "timings": {"started": 1700000000000, "completed": 1700000001000},
+    "executions": [
+      {
+        "id": "i1",
+        "item": {"id": "i1", "name": "Get user"},
+        "cursor": {"iteration": 0, "position": 0, "length": 2, "cycles": 1, "ref": "r1"},
+        "request": {"method": "GET", "url": {"protocol": "http", "host": ["localhost"], "port": "3000", "path": ["api", "users", "1"]}, "header": []},
+        "response": {"code": 200, "status": "OK", "header": [{"key": "Content-Type", "value": "application/json"}], "stream": {"type": "Buffer", "data": "eyJuYW1lIjogIkFkYSJ9"}, "responseTime": 10},
+        "assertions": [{"assertion": "status is 200"}]
+      },
+      {
+        "id": "i2",
+        "item": {"id": "i2", "name": "Get other"},
+        "cursor": {"iteration": 0, "position": 1, "length": 2, "cycles": 1, "ref": "r2"},
+        "request": {"method": "GET", "url": {"protocol": "http", "host": ["localhost"], "port": "3000", "path": ["api", "users", "2"]}, "header": []},
+        "requestError": {"code": "ECONNREFUSED", "message": "connect ECONNREFUSED 127.0.0.1:3000"},
+        "assertions": [{"assertion": "status is 200", "error": {"name": "AssertionError", "message": "expected response to have status code 200"}}]
+      }
+    ]
+  }
+}`
+
+// brunoTransportReport is a bru run whose second request got a response of status 0, the server being down
+const brunoTransportReport = `{
+  "iterationIndex": 0,
+  "summary": {"totalRequests": 2, "passedRequests": 1, "failedRequests": 0, "skippedRequests": 0, "errorRequests": 1},
+  "results": [
+    {
+      "test": {"filename": "Get user.bru"},
+      "request": {"method": "GET", "url": "http://localhost:3000/api/users/1", "headers": {}},
+      "response": {"status": 200, "statusText": "OK", "headers": {"content-type": "application/json"}, "data": {"name": "Ada"}, "responseTime": 10},
+      "status": "pass"
+    },
+    {
+      "test": {"filename": "Get other.bru"},
+      "request": {"method": "GET", "url": "http://localhost:3000/api/users/2", "headers": {}},
+      "response": {"status": 0, "statusText": "", "headers": {}, "data": null, "responseTime": 0},
+      "error": "connect ECONNREFUSED 127.0.0.1:3000",
+      "status": "error"
+    }
+  ]
+}`
+
+func TestTransportErrors(t *testing.T) {
+	scenario := []struct {
+		parser         Parser
+		report         string
+		transportError string
+	}{
+		{PostmanParser{}, newmanReport, "connect ECONNREFUSED 127.0.0.1:3000"},
+		{BrunoParser{}, brunoTransportReport, "connect ECONNREFUSED 127.0.0.1:3000"},
+	}
+	for i, elem := range scenario {
+		results, err := elem.parser.Parse([]string{writeParserFile(t, "report.json", elem.report)}, newParserRouter(t), validator.Config{})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if len(results) != 2 || results[1].Response.TransportError != elem.transportError {
+			t.Fatal(i, len(results), results[len(results)-1].Response.TransportError)
+		}
+
+		validated, err := validator.Validate(results, context.Background())
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		// The request was sent as the spec expects it, only its response is missing
+		if validated[2].GetStatus() != validator.Success || validated[3].GetStatus() != validator.NotExecuted {
+			t.Fatal(i, validated[2].GetStatus(), validated[3].GetStatus())
+		}
+
+		report, err := reports.GenerateReport(validated)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		summary := report.Summary
+		if summary.TotalRequests != 2 || summary.PassedRequests != 2 || summary.FailedRequests != 0 {
+			t.Fatal(i, summary)
+		}
+		if summary.TotalResponses != 2 || summary.PassedResponses != 1 || summary.NotExecutedResponses != 1 || summary.FailedResponses != 0 {
+			t.Fatal(i, summary)
+		}
+	}
+}
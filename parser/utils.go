@@ -1,6 +1,19 @@
 package test_report
 
-import "static-openapivalidator/validator"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"static-openapivalidator/routing"
+	"static-openapivalidator/validator"
+	"strings"
+)
 
 func addResultToArray(array []validator.TestResult, res validator.TestResult, config validator.Config) []validator.TestResult {
 	// Check if request is ignored
@@ -10,12 +23,316 @@ func addResultToArray(array []validator.TestResult, res validator.TestResult, co
 		}
 	}
 
-	// Check if request is ignored
+	// Check if response is ignored
 	for _, path := range config.IgnoredResponses {
-		if path.Match(res.Id) {
+		if path.Match(res.Id) && res.Response != nil {
 			res.Response.Ignored = true
 		}
 	}
 
 	return append(array, res)
 }
+
+// noRouteFound is the parsing error of the responses to unrouted requests
+const noRouteFound = "no route found"
+
+// invalidUrl starts the parsing error of the requests whose URL cannot be parsed, they are never routed
+const invalidUrl = "invalid URL"
+
+// newTestRequest builds the request to validate and looks up its route, in the spec it is bound to
+// The URL must already be escaped, and the headers are sent as given
+func newTestRequest(method, rawUrl string, header http.Header, body Body, parsingError string, router *routing.Router, config validator.Config) (*validator.TestRequest, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		// Such as an unresolved {{variable}} in the host, only this exchange is affected
+		httpReq := &http.Request{Method: method, URL: &url.URL{Path: rawUrl}, Header: header}
+		return &validator.TestRequest{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{Request: httpReq},
+			Body:                   body.Formatted,
+			BodyEncoding:           body.Encoding,
+			ParsingError:           fmt.Sprintf("%s %s: %v", invalidUrl, rawUrl, err),
+		}, nil
+	}
+
+	httpReq, err := http.NewRequest(method, parsedUrl.String(), body.Reader())
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		httpReq.Header[key] = values
+	}
+	originalUrl := ""
+	if router.Rewrite.Apply(httpReq) {
+		originalUrl = parsedUrl.String()
+	}
+
+	ignored := false
+	for _, path := range config.IgnoredRoutes {
+		if path.Match(httpReq.URL.Path) {
+			ignored = true
+		}
+	}
+
+	request := &validator.TestRequest{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: httpReq},
+		Body:                   body.Formatted,
+		BodyEncoding:           body.Encoding,
+		ParsingError:           parsingError,
+		Ignored:                ignored,
+		OriginalUrl:            originalUrl,
+	}
+	return request, routeRequest(request, router)
+}
+
+// routeRequest looks up the route of a request, in the spec it is bound to
+// A routing failure replaces the parsing error of the request
+func routeRequest(request *validator.TestRequest, router *routing.Router) error {
+	httpReq := request.Request
+	match := router.Match(httpReq)
+	if match.Err != nil {
+		if errors.Is(match.Err, routing.ErrNoSpec) {
+			request.Unmatched = true
+			request.ParsingError = fmt.Sprintf("no spec matches %s %s", httpReq.Method, httpReq.URL.String())
+		} else if errors.Is(match.Err, routers.ErrPathNotFound) {
+			request.ParsingError = fmt.Sprintf("could not find route for %s %s: %v", httpReq.Method, httpReq.URL.String(), match.Err)
+		} else if errors.Is(match.Err, routers.ErrMethodNotAllowed) {
+			request.ParsingError = fmt.Sprintf("bad method for %s %s: %v", httpReq.Method, httpReq.URL.String(), match.Err)
+		} else {
+			return match.Err
+		}
+	} else {
+		// Disabling security checks
+		match.Route.Spec.Security = nil
+	}
+
+	request.PathParams = match.PathParams
+	request.Route = match.Route
+	request.Spec = match.Spec
+	request.Server = match.Server
+	request.OtherRoutes = match.OtherRoutes
+	request.BodyValidator = match.BodyValidator
+	request.Locations = match.Locations
+	return nil
+}
+
+// Reroute copies test results, and routes the copies with another router, such as the one of a candidate spec
+// The bodies are read once and given to both the results and their copies, so that both can be validated
+func Reroute(results []validator.TestResult, router *routing.Router) ([]validator.TestResult, error) {
+	rerouted := make([]validator.TestResult, len(results))
+	for i, result := range results {
+		rerouted[i] = result
+		if result.Request == nil {
+			continue
+		}
+
+		httpReq := result.Request.Request.Clone(result.Request.Request.Context())
+		if result.Request.Request.GetBody != nil {
+			body, err := result.Request.Request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			httpReq.Body = body
+		}
+		input := *result.Request.RequestValidationInput
+		input.Request = httpReq
+		request := &validator.TestRequest{
+			RequestValidationInput: &input,
+			Body:                   result.Request.Body,
+			BodyEncoding:           result.Request.BodyEncoding,
+			Ignored:                result.Request.Ignored,
+			OriginalUrl:            result.Request.OriginalUrl,
+		}
+		// The parsing error of an unrouted request is the routing one, unless its URL is invalid
+		if result.Request.Route != nil || strings.HasPrefix(result.Request.ParsingError, invalidUrl) {
+			request.ParsingError = result.Request.ParsingError
+		}
+		if !strings.HasPrefix(request.ParsingError, invalidUrl) {
+			if err := routeRequest(request, router); err != nil {
+				return nil, err
+			}
+		}
+		rerouted[i].Request = request
+
+		if result.Response != nil {
+			response := *result.Response
+			responseInput := *result.Response.ResponseValidationInput
+			responseInput.RequestValidationInput = request.RequestValidationInput
+			if result.Response.ResponseValidationInput.Body != nil {
+				data, err := io.ReadAll(result.Response.ResponseValidationInput.Body)
+				if err != nil {
+					return nil, err
+				}
+				result.Response.ResponseValidationInput.Body = io.NopCloser(bytes.NewReader(data))
+				responseInput.Body = io.NopCloser(bytes.NewReader(data))
+			}
+			response.ResponseValidationInput = &responseInput
+			if response.ParsingError == noRouteFound {
+				response.ParsingError = ""
+			}
+			if request.Route == nil {
+				response.ParsingError = noRouteFound
+			}
+			rerouted[i].Response = &response
+		}
+	}
+	return rerouted, nil
+}
+
+var idPlaceholder = regexp.MustCompile(`\{\w+}`)
+
+// formatIdTemplate replaces the {placeholders} of an id template with their values
+// Path segments where every placeholder is empty are dropped, so that optional components do not leave blanks
+func formatIdTemplate(template string, values map[string]string) string {
+	var final []string
+	for _, segment := range strings.Split(template, "/") {
+		hasPlaceholder, hasValue := false, false
+		segment = idPlaceholder.ReplaceAllStringFunc(segment, func(placeholder string) string {
+			value, known := values[strings.Trim(placeholder, "{}")]
+			if !known {
+				return placeholder
+			}
+			hasPlaceholder = true
+			hasValue = hasValue || value != ""
+			return value
+		})
+		if segment != "" && (!hasPlaceholder || hasValue) {
+			final = append(final, segment)
+		}
+	}
+	return strings.Join(final, "/")
+}
+
+// translateExchanges translates the exchanges of reports or collections, each into its test results
+// Ids are made unique before the ignore globs of config are matched against them
+func translateExchanges[E any](exchanges []E, translate func(E) ([]validator.TestResult, error), config validator.Config) ([]validator.TestResult, error) {
+	var translated []validator.TestResult
+	for i := range exchanges {
+		results, err := translate(exchanges[i])
+		if err != nil {
+			return nil, err
+		}
+		for j := range results {
+			addEncodingInfos(&results[j])
+		}
+		translated = append(translated, results...)
+	}
+	disambiguateIds(translated)
+
+	var final []validator.TestResult
+	for i := range translated {
+		final = addResultToArray(final, translated[i], config)
+	}
+	return final, nil
+}
+
+// addEncodingInfos records the encodings the bodies were decoded from, their Content-Encoding being removed from the validated headers
+func addEncodingInfos(result *validator.TestResult) {
+	encodings := map[string]string{}
+	if result.Request != nil && result.Request.BodyEncoding != "" {
+		encodings["requestEncoding"] = result.Request.BodyEncoding
+	}
+	if result.Response != nil && result.Response.BodyEncoding != "" {
+		encodings["responseEncoding"] = result.Response.BodyEncoding
+	}
+	if len(encodings) == 0 {
+		return
+	}
+	if result.AdditionalInfos == nil {
+		result.AdditionalInfos = make(map[string]string)
+	}
+	for key, value := range encodings {
+		result.AdditionalInfos[key] = value
+	}
+}
+
+// disambiguateIds suffixes ids that appear more than once with their occurrence number
+func disambiguateIds(results []validator.TestResult) {
+	occurrences := make(map[string]int)
+	for i := range results {
+		occurrences[results[i].Id]++
+	}
+	seen := make(map[string]int)
+	for i := range results {
+		id := results[i].Id
+		if occurrences[id] > 1 {
+			seen[id]++
+			if seen[id] > 1 {
+				results[i].Id = fmt.Sprintf("%s (%d)", id, seen[id])
+			}
+		}
+	}
+}
+
+const (
+	unreservedCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"
+	// Sub delimiters allowed in a path segment, as defined by RFC 3986
+	pathSegmentCharacters = unreservedCharacters + "!$&'()*+,;=:@"
+	// Characters allowed in a query key or value, without the ones separating parameters
+	queryComponentCharacters = unreservedCharacters + "!$'()*+,;:@/?"
+)
+
+// escapePathSegment percent-encodes a path segment, keeping the escape sequences already present
+func escapePathSegment(segment string) string {
+	return escapeComponent(segment, pathSegmentCharacters)
+}
+
+// escapeQueryComponent percent-encodes a query key or value, keeping the escape sequences already present
+func escapeQueryComponent(component string) string {
+	return escapeComponent(component, queryComponentCharacters)
+}
+
+func escapeComponent(s, allowed string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			// Already escaped
+			sb.WriteByte(c)
+		} else if strings.IndexByte(allowed, c) >= 0 {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return sb.String()
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// escapeRawUrl escapes an URL as typed by a user, without escaping it twice
+// The fragment is dropped as it is never sent
+func escapeRawUrl(raw string) string {
+	raw, _, _ = strings.Cut(raw, "#")
+	rawPath, rawQuery, hasQuery := strings.Cut(raw, "?")
+
+	var prefix string
+	if scheme, rest, found := strings.Cut(rawPath, "://"); found {
+		host, path, hasPath := strings.Cut(rest, "/")
+		prefix = scheme + "://" + host
+		rawPath = ""
+		if hasPath {
+			rawPath = "/" + path
+		}
+	}
+
+	segments := strings.Split(rawPath, "/")
+	for i := range segments {
+		segments[i] = escapePathSegment(segments[i])
+	}
+	escaped := prefix + strings.Join(segments, "/")
+
+	if hasQuery && rawQuery != "" {
+		pairs := strings.Split(rawQuery, "&")
+		for i := range pairs {
+			key, value, hasValue := strings.Cut(pairs[i], "=")
+			pairs[i] = escapeQueryComponent(key)
+			if hasValue {
+				pairs[i] += "=" + escapeQueryComponent(value)
+			}
+		}
+		escaped += "?" + strings.Join(pairs, "&")
+	}
+	return escaped
+}
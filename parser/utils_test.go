@@ -0,0 +1,33 @@
+package test_report
+
+import "testing"
+
+func TestEscapeRawUrl(t *testing.T) {
+	scenario := map[string]string{
+		"http://localhost/users/john doe?limit=10&tags=a b&tags=c": "http://localhost/users/john%20doe?limit=10&tags=a%20b&tags=c",
+		"http://localhost/users/john%20doe?name=%C3%A9&q=50%":      "http://localhost/users/john%20doe?name=%C3%A9&q=50%25",
+		"http://localhost:8080/files/a%2Fb?filter=x=1&flag":        "http://localhost:8080/files/a%2Fb?filter=x%3D1&flag",
+		"http://localhost/search?q=a&b#fragment":                   "http://localhost/search?q=a&b",
+	}
+	for input, expected := range scenario {
+		result := escapeRawUrl(input)
+		if result != expected {
+			t.Fatal(input, result)
+		}
+	}
+}
+
+func TestFormatIdTemplate(t *testing.T) {
+	values := map[string]string{"file": "", "folder": "Users/Admin", "name": "Get {name}", "iteration": "2"}
+	scenario := map[string]string{
+		"{file}/{folder}/{name}/iteration {iteration}": "Users/Admin/Get {name}/iteration 2",
+		"{name} #{iteration}":                          "Get {name} #2",
+		"{file}/{unknown}/{name}":                      "{unknown}/Get {name}",
+	}
+	for template, expected := range scenario {
+		result := formatIdTemplate(template, values)
+		if result != expected {
+			t.Fatal(template, result)
+		}
+	}
+}
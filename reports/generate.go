@@ -6,7 +6,7 @@ import (
 )
 
 func GenerateReport(results []validator.ValidationResult) (Report, error) {
-	var totalRequests, passedRequests, warnRequests, ignoredRequests, totalResponses, warnResponses, passedResponses, ignoredResponses int
+	var totalRequests, passedRequests, warnRequests, ignoredRequests, unmatchedRequests, totalResponses, warnResponses, passedResponses, ignoredResponses, notExecutedResponses, unmatchedResponses int
 	for i := range results {
 		switch v := results[i].(type) {
 		case *validator.RequestValidationResult:
@@ -17,6 +17,8 @@ func GenerateReport(results []validator.ValidationResult) (Report, error) {
 				warnRequests++
 			} else if v.Status == validator.Ignored {
 				ignoredRequests++
+			} else if v.Status == validator.Unmatched {
+				unmatchedRequests++
 			}
 		case *validator.ResponseValidationResult:
 			totalResponses++
@@ -26,6 +28,10 @@ func GenerateReport(results []validator.ValidationResult) (Report, error) {
 				warnResponses++
 			} else if v.Status == validator.Ignored {
 				ignoredResponses++
+			} else if v.Status == validator.NotExecuted {
+				notExecutedResponses++
+			} else if v.Status == validator.Unmatched {
+				unmatchedResponses++
 			}
 		default:
 			return Report{}, errors.New("got unknown type")
@@ -33,17 +39,125 @@ func GenerateReport(results []validator.ValidationResult) (Report, error) {
 	}
 	return Report{
 		Summary: Summary{
-			TotalRequests:    totalRequests,
-			PassedRequests:   passedRequests,
-			WarnRequests:     warnRequests,
-			IgnoredRequests:  ignoredRequests,
-			FailedRequests:   totalRequests - passedRequests - warnRequests - ignoredRequests,
-			TotalResponses:   totalResponses,
-			PassedResponses:  passedResponses,
-			WarnResponses:    warnResponses,
-			IgnoredResponses: ignoredResponses,
-			FailedResponses:  totalResponses - passedResponses - warnResponses - ignoredResponses,
+			TotalRequests:        totalRequests,
+			PassedRequests:       passedRequests,
+			WarnRequests:         warnRequests,
+			IgnoredRequests:      ignoredRequests,
+			FailedRequests:       totalRequests - passedRequests - warnRequests - ignoredRequests - unmatchedRequests,
+			TotalResponses:       totalResponses,
+			PassedResponses:      passedResponses,
+			WarnResponses:        warnResponses,
+			IgnoredResponses:     ignoredResponses,
+			NotExecutedResponses: notExecutedResponses,
+			FailedResponses:      totalResponses - passedResponses - warnResponses - ignoredResponses - notExecutedResponses - unmatchedResponses,
+			UnmatchedRequests:    unmatchedRequests,
+			UnmatchedResponses:   unmatchedResponses,
+			Specs:                summarizeSpecs(results),
+			Correlation:          correlate(results),
+			Candidate:            summarizeCandidate(results),
 		},
 		Results: results,
 	}, nil
 }
+
+func summarizeSpecs(results []validator.ValidationResult) []SpecSummary {
+	var specs []SpecSummary
+	indexes := make(map[string]int)
+	for i := range results {
+		name := results[i].GetSpec()
+		if name == "" {
+			continue
+		}
+		index, seen := indexes[name]
+		if !seen {
+			index = len(specs)
+			indexes[name] = index
+			specs = append(specs, SpecSummary{Name: name})
+		}
+		spec := &specs[index]
+		passed := results[i].GetStatus() == validator.Success
+		failed := results[i].GetStatus() == validator.Failure
+		if results[i].GetType() == "request" {
+			spec.TotalRequests++
+			if passed {
+				spec.PassedRequests++
+			} else if failed {
+				spec.FailedRequests++
+			}
+		} else {
+			spec.TotalResponses++
+			if passed {
+				spec.PassedResponses++
+			} else if failed {
+				spec.FailedResponses++
+			}
+		}
+	}
+	return specs
+}
+
+// summarizeCandidate counts the results by transition, it returns nil when they were not compared with a candidate spec
+func summarizeCandidate(results []validator.ValidationResult) *CandidateSummary {
+	var summary *CandidateSummary
+	for i := range results {
+		candidate := results[i].GetCandidate()
+		if candidate == nil {
+			continue
+		}
+		if summary == nil {
+			summary = &CandidateSummary{}
+		}
+		switch candidate.Transition {
+		case validator.Regressed:
+			summary.Regressed++
+		case validator.Fixed:
+			summary.Fixed++
+		case validator.NewlyUnmatched:
+			summary.NewlyUnmatched++
+		case validator.Changed:
+			summary.Changed++
+		default:
+			summary.Unchanged++
+		}
+	}
+	return summary
+}
+
+func correlate(results []validator.ValidationResult) Correlation {
+	var ids []string
+	contractFailed := make(map[string]bool)
+	assertions := make(map[string][]validator.Assertion)
+	for i := range results {
+		id := results[i].GetTestId()
+		if _, seen := contractFailed[id]; !seen {
+			ids = append(ids, id)
+			// Request and response share the assertions of their test
+			assertions[id] = results[i].GetAssertions()
+		}
+		contractFailed[id] = contractFailed[id] || results[i].GetStatus() == validator.Failure
+	}
+
+	var correlation Correlation
+	for _, id := range ids {
+		executed, functionalFailed := false, false
+		for _, assertion := range assertions[id] {
+			if assertion.Status != validator.Ignored {
+				executed = true
+				functionalFailed = functionalFailed || assertion.Status == validator.Failure
+			}
+		}
+		switch {
+		case !executed:
+			correlation.NoAssertions++
+		case functionalFailed && contractFailed[id]:
+			correlation.FunctionalFailedContractFailed++
+		case functionalFailed:
+			correlation.FunctionalFailedContractPassed++
+		case contractFailed[id]:
+			correlation.FunctionalPassedContractFailed++
+		default:
+			correlation.FunctionalPassedContractPassed++
+		}
+	}
+	return correlation
+}
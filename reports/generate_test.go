@@ -0,0 +1,48 @@
+package reports
+
+import (
+	"static-openapivalidator/validator"
+	"testing"
+)
+
+func TestCorrelate(t *testing.T) {
+	passed := validator.Assertion{Name: "status is 200", Status: validator.Success}
+	failed := validator.Assertion{Name: "status is 200", Status: validator.Failure, Error: "expected 404 to equal 200"}
+	skipped := validator.Assertion{Name: "status is 200", Status: validator.Ignored}
+	scenario := []struct {
+		assertions []validator.Assertion
+		request    string
+		response   string
+		expected   Correlation
+	}{
+		{[]validator.Assertion{passed}, validator.Success, validator.Success, Correlation{FunctionalPassedContractPassed: 1}},
+		{[]validator.Assertion{passed}, validator.Success, validator.Failure, Correlation{FunctionalPassedContractFailed: 1}},
+		{[]validator.Assertion{passed, failed}, validator.Success, validator.Success, Correlation{FunctionalFailedContractPassed: 1}},
+		{[]validator.Assertion{failed}, validator.Failure, validator.Success, Correlation{FunctionalFailedContractFailed: 1}},
+		// Warnings are not contract failures, and skipped assertions are no functional outcome
+		{[]validator.Assertion{skipped, passed}, validator.Warning, validator.Success, Correlation{FunctionalPassedContractPassed: 1}},
+		{[]validator.Assertion{skipped}, validator.Failure, validator.Failure, Correlation{NoAssertions: 1}},
+		{nil, validator.Success, validator.Success, Correlation{NoAssertions: 1}},
+	}
+	for i, elem := range scenario {
+		results := []validator.ValidationResult{
+			&validator.RequestValidationResult{TestId: "Get user", Status: elem.request, Assertions: elem.assertions},
+			&validator.ResponseValidationResult{TestId: "Get user", Status: elem.response, Assertions: elem.assertions},
+		}
+		if correlation := correlate(results); correlation != elem.expected {
+			t.Fatal(i, correlation)
+		}
+	}
+
+	// Each test is counted once, whatever its number of results
+	results := []validator.ValidationResult{
+		&validator.RequestValidationResult{TestId: "Get user", Status: validator.Success, Assertions: []validator.Assertion{passed}},
+		&validator.ResponseValidationResult{TestId: "Get user", Status: validator.Failure, Assertions: []validator.Assertion{passed}},
+		&validator.RequestValidationResult{TestId: "Get other", Status: validator.Success, Assertions: []validator.Assertion{failed}},
+		&validator.ResponseValidationResult{TestId: "Get other", Status: validator.Success, Assertions: []validator.Assertion{failed}},
+	}
+	expected := Correlation{FunctionalPassedContractFailed: 1, FunctionalFailedContractPassed: 1}
+	if correlation := correlate(results); correlation != expected {
+		t.Fatal(correlation)
+	}
+}
@@ -0,0 +1,54 @@
+package json
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"static-openapivalidator/reports"
+	"static-openapivalidator/validator"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	infos := map[string]string{"iteration": "2", "testStatus": "fail", "responseEncoding": "gzip"}
+	results := []validator.ValidationResult{
+		&validator.RequestValidationResult{TestId: "Get user", Url: "http://localhost:3000/api/users/1", Method: "GET", Status: validator.Success, AdditionalInfos: infos},
+		&validator.ResponseValidationResult{TestId: "Get user", Url: "http://localhost:3000/api/users/1", Status: validator.Success, AdditionalInfos: infos},
+	}
+	report, err := reports.GenerateReport(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err = NewReporter(path).Generate(report); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Results []struct {
+			Type  string            `json:"type"`
+			Infos map[string]string `json:"infos"`
+		} `json:"results"`
+	}
+	if err = json.Unmarshal(generated, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	// The metadata of the parsers reaches every result, under infos
+	if len(decoded.Results) != 2 {
+		t.Fatal(len(decoded.Results))
+	}
+	for i, result := range decoded.Results {
+		if len(result.Infos) != len(infos) {
+			t.Fatal(i, result.Type, result.Infos)
+		}
+		for name, value := range infos {
+			if result.Infos[name] != value {
+				t.Fatal(i, result.Type, name, result.Infos[name])
+			}
+		}
+	}
+}
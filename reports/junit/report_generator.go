@@ -6,6 +6,7 @@ import (
 	"fmt"
 	junit_xml "github.com/jstemmer/go-junit-report/v2/junit"
 	"os"
+	"sort"
 	"static-openapivalidator/logger"
 	"static-openapivalidator/reports"
 	"static-openapivalidator/validator"
@@ -20,31 +21,90 @@ type Reporter struct {
 	filePath string
 }
 
+// testsuites, testsuite and testcase are the JUNIT types, with per testcase properties
+// They are declared rather than embedded from go-junit-report, whose types cannot hold properties on testcases
+type testsuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Tests    int         `xml:"tests,attr,omitempty"`
+	Errors   int         `xml:"errors,attr,omitempty"`
+	Failures int         `xml:"failures,attr,omitempty"`
+	Skipped  int         `xml:"skipped,attr,omitempty"`
+	Suites   []testsuite `xml:"testsuite,omitempty"`
+}
+
+type testsuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Errors    int        `xml:"errors,attr"`
+	ID        int        `xml:"id,attr"`
+	Skipped   int        `xml:"skipped,attr,omitempty"`
+	Time      string     `xml:"time,attr"`
+	Testcases []testcase `xml:"testcase,omitempty"`
+}
+
+type testcase struct {
+	Name       string                `xml:"name,attr"`
+	Classname  string                `xml:"classname,attr"`
+	Properties *[]junit_xml.Property `xml:"properties>property,omitempty"`
+	Skipped    *junit_xml.Result     `xml:"skipped,omitempty"`
+	Error      *junit_xml.Result     `xml:"error,omitempty"`
+	Failure    *junit_xml.Result     `xml:"failure,omitempty"`
+	SystemOut  *junit_xml.Output     `xml:"system-out,omitempty"`
+}
+
+// addTestcase adds a testcase to the suite and counts its outcome
+func (s *testsuite) addTestcase(tc testcase) {
+	s.Testcases = append(s.Testcases, tc)
+	s.Tests++
+	if tc.Error != nil {
+		s.Errors++
+	}
+	if tc.Failure != nil {
+		s.Failures++
+	}
+	if tc.Skipped != nil {
+		s.Skipped++
+	}
+}
+
+// addSuite adds a suite and its totals
+func (s *testsuites) addSuite(suite testsuite) {
+	s.Suites = append(s.Suites, suite)
+	s.Tests += suite.Tests
+	s.Errors += suite.Errors
+	s.Failures += suite.Failures
+	s.Skipped += suite.Skipped
+}
+
 func (r Reporter) Generate(report reports.Report) error {
 	logger.Log("Reporting: generating JUNIT report")
 
 	// Group results by URL
 	groups := make(map[string][]validator.ValidationResult)
+	var urls []string
 
 	for i := range report.Results {
 		url := report.Results[i].GetUrl()
 		if len(groups[url]) == 0 {
-			groups[url] = []validator.ValidationResult{report.Results[i]}
-		} else {
-			groups[url] = append(groups[url], report.Results[i])
+			urls = append(urls, url)
 		}
+		groups[url] = append(groups[url], report.Results[i])
 	}
+	// Suites are sorted by URL, for the report not to change from one run to the other
+	sort.Strings(urls)
 
-	var suites junit_xml.Testsuites
-	for url, tests := range groups {
-		suite := junit_xml.Testsuite{
+	var suites testsuites
+	for _, url := range urls {
+		tests := groups[url]
+		suite := testsuite{
 			Name: url,
 			ID:   len(tests),
 		}
 		for _, test := range tests {
-			suite.AddTestcase(createTestcaseForTest(url, test))
+			suite.addTestcase(createTestcaseForTest(url, test))
 		}
-		suites.AddSuite(suite)
+		suites.addSuite(suite)
 	}
 
 	bytes, err := xml.Marshal(suites)
@@ -54,12 +114,13 @@ func (r Reporter) Generate(report reports.Report) error {
 	return os.WriteFile(r.filePath, bytes, 0644)
 }
 
-func createTestcaseForTest(url string, test validator.ValidationResult) junit_xml.Testcase {
+func createTestcaseForTest(url string, test validator.ValidationResult) testcase {
 	testName := fmt.Sprintf("%s - %s", test.GetTestId(), test.GetType())
 
-	tc := junit_xml.Testcase{
-		Classname: url,
-		Name:      testName,
+	tc := testcase{
+		Name:       testName,
+		Classname:  url,
+		Properties: createProperties(test),
 	}
 	switch test.GetStatus() {
 	case validator.Failure:
@@ -71,12 +132,62 @@ func createTestcaseForTest(url string, test validator.ValidationResult) junit_xm
 		tc.Skipped = &junit_xml.Result{
 			Message: "Skipped",
 		}
+	case validator.NotExecuted:
+		tc.Error = &junit_xml.Result{
+			Message: "Not executed",
+			Data:    formatOutput(test),
+		}
+	case validator.Unmatched:
+		tc.Skipped = &junit_xml.Result{
+			Message: "Unmatched",
+			Data:    formatOutput(test),
+		}
 	default:
 		tc.SystemOut = &junit_xml.Output{Data: formatOutput(test)}
 	}
 	return tc
 }
 
+func createProperties(test validator.ValidationResult) *[]junit_xml.Property {
+	var properties []junit_xml.Property
+	for name, value := range test.GetAdditionalInfos() {
+		properties = append(properties, junit_xml.Property{Name: name, Value: value})
+	}
+	if spec := test.GetSpec(); spec != "" {
+		properties = append(properties, junit_xml.Property{Name: "spec", Value: spec})
+	}
+	if server := test.GetServer(); server != "" {
+		properties = append(properties, junit_xml.Property{Name: "server", Value: server})
+	}
+	if originalUrl := test.GetOriginalUrl(); originalUrl != "" {
+		properties = append(properties, junit_xml.Property{Name: "originalUrl", Value: originalUrl})
+		properties = append(properties, junit_xml.Property{Name: "rewrittenUrl", Value: test.GetRewrittenUrl()})
+	}
+	if ambiguousRoutes := test.GetAmbiguousRoutes(); len(ambiguousRoutes) > 0 {
+		properties = append(properties, junit_xml.Property{Name: "route", Value: test.GetRoute()})
+		properties = append(properties, junit_xml.Property{Name: "ambiguousRoutes", Value: strings.Join(ambiguousRoutes, ", ")})
+	}
+	if schemaSource := test.GetSchemaSource(); schemaSource != "" {
+		properties = append(properties, junit_xml.Property{Name: "schemaSource", Value: schemaSource})
+	}
+	if candidate := test.GetCandidate(); candidate != nil {
+		properties = append(properties, junit_xml.Property{Name: "candidateStatus", Value: candidate.Status})
+		if candidate.Transition != "" {
+			properties = append(properties, junit_xml.Property{Name: "candidateTransition", Value: candidate.Transition})
+		}
+		if candidate.ErrorSummary != "" {
+			properties = append(properties, junit_xml.Property{Name: "candidateError", Value: candidate.ErrorSummary})
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].Name < properties[j].Name
+	})
+	return &properties
+}
+
 func formatOutput(test validator.ValidationResult) string {
 
 	var sb strings.Builder
@@ -90,7 +201,11 @@ func formatOutput(test validator.ValidationResult) string {
 	}
 
 	if test.GetBody() != "" {
-		sb.WriteString("Body:\n")
+		if test.GetBodyEncoding() != "" {
+			sb.WriteString(fmt.Sprintf("Body (decoded from %s):\n", test.GetBodyEncoding()))
+		} else {
+			sb.WriteString("Body:\n")
+		}
 		sb.WriteString(indent(test.GetBody(), "\t"))
 		sb.WriteString("\n")
 	}
@@ -101,10 +216,25 @@ func formatOutput(test validator.ValidationResult) string {
 		prefix := "Error summary"
 		if test.GetStatus() == validator.Warning {
 			prefix = "Warning"
+		} else if test.GetStatus() == validator.NotExecuted {
+			prefix = "Not executed"
+		} else if test.GetStatus() == validator.Unmatched {
+			prefix = "Unmatched"
 		}
 		sb.WriteString(fmt.Sprintf("%s: %s \n", prefix, errorSummary))
 	}
 
+	if len(test.GetAssertions()) > 0 {
+		sb.WriteString("Assertions:\n")
+		for _, assertion := range test.GetAssertions() {
+			sb.WriteString(fmt.Sprintf("\t[%s] %s", assertion.Status, assertion.Name))
+			if assertion.Error != "" {
+				sb.WriteString(": " + assertion.Error)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
 	if len(test.GetErrors()) > 0 {
 		for i := range test.GetErrors() {
 			sb.WriteString(fmt.Sprintf("Error #%d:\n", i+1))
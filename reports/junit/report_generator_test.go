@@ -0,0 +1,51 @@
+package junit
+
+import (
+	"os"
+	"path/filepath"
+	"static-openapivalidator/reports"
+	"static-openapivalidator/validator"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	infos := map[string]string{"iteration": "1", "responseEncoding": "gzip"}
+	report := reports.Report{Results: []validator.ValidationResult{
+		&validator.RequestValidationResult{TestId: "Get user", Url: "http://localhost:3000/api/users/1", Method: "GET", Status: validator.Success,
+			AdditionalInfos: infos, Spec: "users", Server: "http://localhost:3000/api"},
+		&validator.ResponseValidationResult{TestId: "Get user", Url: "http://localhost:3000/api/users/1", Status: validator.Failure,
+			Body: `{"name": 1}`, BodyEncoding: "gzip", ErrorSummary: "response body doesn't match schema",
+			AdditionalInfos: infos, Spec: "users", Server: "http://localhost:3000/api"},
+		&validator.RequestValidationResult{TestId: "Get admin", Url: "http://localhost:3000/api/admins/2", Method: "GET", Status: validator.Success},
+		&validator.ResponseValidationResult{TestId: "Get admin", Url: "http://localhost:3000/api/admins/2", Status: validator.NotExecuted,
+			ErrorSummary: "connect ECONNREFUSED 127.0.0.1:3000"},
+	}}
+
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := NewReporter(path).Generate(report); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generated) != expectedReport {
+		t.Fatal(string(generated))
+	}
+}
+
+// expectedReport sorts the suites by URL, and the properties of each testcase by name
+const expectedReport = "<testsuites tests=\"4\" errors=\"1\" failures=\"1\">" +
+	"<testsuite name=\"http://localhost:3000/api/admins/2\" tests=\"2\" failures=\"0\" errors=\"1\" id=\"2\" time=\"\">" +
+	"<testcase name=\"Get admin - request\" classname=\"http://localhost:3000/api/admins/2\"><system-out></system-out></testcase>" +
+	"<testcase name=\"Get admin - response\" classname=\"http://localhost:3000/api/admins/2\"><error message=\"Not executed\"><![CDATA[Not executed: connect ECONNREFUSED 127.0.0.1:3000 \n]]></error></testcase>" +
+	"</testsuite>" +
+	"<testsuite name=\"http://localhost:3000/api/users/1\" tests=\"2\" failures=\"1\" errors=\"0\" id=\"2\" time=\"\">" +
+	"<testcase name=\"Get user - request\" classname=\"http://localhost:3000/api/users/1\">" +
+	"<properties><property name=\"iteration\" value=\"1\"></property><property name=\"responseEncoding\" value=\"gzip\"></property><property name=\"server\" value=\"http://localhost:3000/api\"></property><property name=\"spec\" value=\"users\"></property></properties>" +
+	"<system-out></system-out></testcase>" +
+	"<testcase name=\"Get user - response\" classname=\"http://localhost:3000/api/users/1\">" +
+	"<properties><property name=\"iteration\" value=\"1\"></property><property name=\"responseEncoding\" value=\"gzip\"></property><property name=\"server\" value=\"http://localhost:3000/api\"></property><property name=\"spec\" value=\"users\"></property></properties>" +
+	"<failure message=\"Failed\"><![CDATA[Body (decoded from gzip):\n\t{\"name\": 1}\nError summary: response body doesn't match schema \n]]></failure></testcase>" +
+	"</testsuite>" +
+	"</testsuites>"
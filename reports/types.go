@@ -3,6 +3,7 @@ package reports
 import (
 	"fmt"
 	"static-openapivalidator/validator"
+	"strings"
 )
 
 type Report struct {
@@ -21,6 +22,53 @@ type Summary struct {
 	WarnResponses    int `json:"warnResponses"`
 	FailedResponses  int `json:"failedResponses"`
 	IgnoredResponses int `json:"ignoredResponses"`
+	// NotExecutedResponses counts the responses never received, they are neither passed nor failed
+	NotExecutedResponses int `json:"notExecutedResponses"`
+	// UnmatchedRequests and UnmatchedResponses count the exchanges bound to none of the specs
+	UnmatchedRequests  int `json:"unmatchedRequests"`
+	UnmatchedResponses int `json:"unmatchedResponses"`
+	// Specs details the results by spec, in the order they first appear in results
+	Specs []SpecSummary `json:"specs,omitempty"`
+	// Correlation crosses the functional outcome of each test with its contract validation
+	Correlation Correlation `json:"correlation"`
+	// Candidate counts the transitions of the exchanges against the candidate spec, when one is given
+	Candidate *CandidateSummary `json:"candidate,omitempty"`
+}
+
+// CandidateSummary counts the results by transition, from the current spec to the candidate one
+type CandidateSummary struct {
+	Regressed      int `json:"regressed"`
+	Fixed          int `json:"fixed"`
+	NewlyUnmatched int `json:"newlyUnmatched"`
+	Changed        int `json:"changed"`
+	Unchanged      int `json:"unchanged"`
+}
+
+// Failed tells whether the candidate spec breaks exchanges that pass against the current one
+func (c CandidateSummary) Failed() bool {
+	return c.Regressed > 0 || c.NewlyUnmatched > 0
+}
+
+// SpecSummary counts the results of the exchanges routed to a spec
+type SpecSummary struct {
+	Name            string `json:"name"`
+	TotalRequests   int    `json:"totalRequests"`
+	PassedRequests  int    `json:"passedRequests"`
+	FailedRequests  int    `json:"failedRequests"`
+	TotalResponses  int    `json:"totalResponses"`
+	PassedResponses int    `json:"passedResponses"`
+	FailedResponses int    `json:"failedResponses"`
+}
+
+// Correlation counts tests by functional and contract outcome
+// A test fails functionally when one of its assertions failed, and fails its contract when its request or response failed validation
+type Correlation struct {
+	FunctionalPassedContractPassed int `json:"functionalPassedContractPassed"`
+	FunctionalPassedContractFailed int `json:"functionalPassedContractFailed"`
+	FunctionalFailedContractPassed int `json:"functionalFailedContractPassed"`
+	FunctionalFailedContractFailed int `json:"functionalFailedContractFailed"`
+	// NoAssertions counts the tests without any functional outcome
+	NoAssertions int `json:"noAssertions"`
 }
 
 func (s Summary) String() string {
@@ -33,7 +81,11 @@ Total responses: %d
 Passed respones: %d
 Warn responses: %d
 Failed reponses: %d
-Ignored responses: %d`,
+Ignored responses: %d
+Not executed responses: %d
+Unmatched requests: %d
+Unmatched responses: %d
+%s%s%s`,
 		s.TotalRequests,
 		s.PassedRequests,
 		s.WarnRequests,
@@ -43,5 +95,57 @@ Ignored responses: %d`,
 		s.PassedResponses,
 		s.WarnResponses,
 		s.FailedResponses,
-		s.IgnoredResponses)
+		s.IgnoredResponses,
+		s.NotExecutedResponses,
+		s.UnmatchedRequests,
+		s.UnmatchedResponses,
+		specSummaries(s.Specs),
+		s.Correlation,
+		candidateSummary(s.Candidate))
+}
+
+// candidateSummary counts the transitions against the candidate spec, when one is given
+func candidateSummary(candidate *CandidateSummary) string {
+	if candidate == nil {
+		return ""
+	}
+	return fmt.Sprintf(`
+Candidate spec regressions: %d
+Candidate spec fixes: %d
+Candidate spec newly unmatched: %d
+Candidate spec other changes: %d
+Candidate spec unchanged: %d`,
+		candidate.Regressed,
+		candidate.Fixed,
+		candidate.NewlyUnmatched,
+		candidate.Changed,
+		candidate.Unchanged)
+}
+
+// specSummaries details the results by spec, when there are several of them
+func specSummaries(specs []SpecSummary) string {
+	if len(specs) < 2 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, spec := range specs {
+		sb.WriteString(fmt.Sprintf("Spec %s: %d/%d requests passed, %d failed, %d/%d responses passed, %d failed\n",
+			spec.Name,
+			spec.PassedRequests, spec.TotalRequests, spec.FailedRequests,
+			spec.PassedResponses, spec.TotalResponses, spec.FailedResponses))
+	}
+	return sb.String()
+}
+
+func (c Correlation) String() string {
+	return fmt.Sprintf(`Functional passed, contract passed: %d
+Functional passed, contract failed: %d
+Functional failed, contract passed: %d
+Functional failed, contract failed: %d
+Without assertions: %d`,
+		c.FunctionalPassedContractPassed,
+		c.FunctionalPassedContractFailed,
+		c.FunctionalFailedContractPassed,
+		c.FunctionalFailedContractFailed,
+		c.NoAssertions)
 }
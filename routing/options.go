@@ -0,0 +1,276 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Router implementations
+const (
+	// RouterGorillaMux prefers the paths without variables, then the longest templates
+	RouterGorillaMux = "gorillamux"
+	// RouterLegacy is the radix tree router of kin-openapi
+	RouterLegacy = "legacy"
+)
+
+// Options adjust how the exchanges are matched against the paths of a spec
+type Options struct {
+	// Implementation is either RouterGorillaMux, the default, or RouterLegacy
+	Implementation string
+	// IgnoreTrailingSlash matches /users/ against /users, and /users against /users/, when the exact path is missing
+	IgnoreTrailingSlash bool
+	// CaseInsensitive matches the paths whatever their case, the values of the path parameters keep theirs
+	CaseInsensitive bool
+}
+
+// specRouter wraps the router of a spec with the options, and lists the other paths matching a request
+type specRouter struct {
+	router  routers.Router
+	doc     *openapi3.T
+	options Options
+	// paths maps the paths of the router to the ones of the spec, when they differ
+	paths map[string]string
+	// pathItems and servers map the lowercased copies the router was built with to the ones of the spec
+	pathItems map[*openapi3.PathItem]*openapi3.PathItem
+	servers   map[*openapi3.Server]*openapi3.Server
+	templates map[string]pathTemplate
+}
+
+// pathTemplate is a path of a spec as a regular expression, along with the names of its variables
+type pathTemplate struct {
+	pattern *regexp.Regexp
+	names   []string
+}
+
+// NewSpecRouter builds the router of a validated spec
+// The paths of the spec are lowercased for the router when matching is case-insensitive, servers included
+// The router is then built with lowercased copies, doc is left as is
+func NewSpecRouter(doc *openapi3.T, options Options) (routers.Router, error) {
+	r := &specRouter{
+		doc:       doc,
+		options:   options,
+		paths:     make(map[string]string),
+		pathItems: make(map[*openapi3.PathItem]*openapi3.PathItem),
+		servers:   make(map[*openapi3.Server]*openapi3.Server),
+		templates: make(map[string]pathTemplate),
+	}
+
+	routed := doc
+	if options.CaseInsensitive {
+		lowered := *doc
+		lowered.Paths = openapi3.NewPaths()
+		for path, pathItem := range doc.Paths.Map() {
+			lowerPath := lowerTemplate(path)
+			if other, ok := r.paths[lowerPath]; ok {
+				return nil, fmt.Errorf("paths %s and %s only differ by their case", other, path)
+			}
+			r.paths[lowerPath] = path
+			loweredItem := *pathItem
+			loweredItem.Servers = r.lowerServers(pathItem.Servers)
+			r.pathItems[&loweredItem] = pathItem
+			lowered.Paths.Set(lowerPath, &loweredItem)
+		}
+		lowered.Servers = r.lowerServers(doc.Servers)
+		routed = &lowered
+	}
+
+	var err error
+	switch options.Implementation {
+	case "", RouterGorillaMux:
+		r.router, err = gorillamux.NewRouter(routed)
+	case RouterLegacy:
+		r.router, err = legacy.NewRouter(routed)
+	default:
+		err = fmt.Errorf("invalid router %q, expected %s or %s", options.Implementation, RouterGorillaMux, RouterLegacy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *specRouter) FindRoute(req *http.Request) (*routers.Route, map[string]string, error) {
+	route, pathParams, err := r.find(req)
+	if err != nil && errors.Is(err, routers.ErrPathNotFound) && r.options.IgnoreTrailingSlash && req.URL.Path != "/" {
+		if alternative, altParams, altErr := r.find(withTrailingSlashToggled(req)); altErr == nil {
+			return alternative, altParams, nil
+		}
+	}
+	return route, pathParams, err
+}
+
+func (r *specRouter) find(req *http.Request) (*routers.Route, map[string]string, error) {
+	if !r.options.CaseInsensitive {
+		return r.router.FindRoute(req)
+	}
+
+	lowered := req.Clone(req.Context())
+	lowered.URL.Path = strings.ToLower(req.URL.Path)
+	lowered.URL.RawPath = ""
+	lowered.URL.Host = strings.ToLower(req.URL.Host)
+	route, pathParams, err := r.router.FindRoute(lowered)
+	if err != nil {
+		return nil, nil, err
+	}
+	route.Path = r.paths[route.Path]
+	route.Spec = r.doc
+	if pathItem, ok := r.pathItems[route.PathItem]; ok {
+		route.PathItem = pathItem
+	}
+	if server, ok := r.servers[route.Server]; ok {
+		route.Server = server
+	}
+
+	// The values of the parameters are taken again from the request, with their case
+	if names, values := r.pathValues(route, req.URL.Path); values != nil {
+		for i, name := range names {
+			pathParams[name] = values[i]
+		}
+	}
+	return route, pathParams, nil
+}
+
+// OtherRoutes lists the other paths of the spec matching a request for the same method, in matching order
+func (r *specRouter) OtherRoutes(req *http.Request, route *routers.Route) []string {
+	var others []string
+	for _, path := range r.doc.Paths.InMatchingOrder() {
+		if path == route.Path || r.doc.Paths.Value(path).GetOperation(req.Method) == nil {
+			continue
+		}
+		other := *route
+		other.Path = path
+		if _, values := r.pathValues(&other, req.URL.Path); values != nil {
+			others = append(others, path)
+		} else if r.options.IgnoreTrailingSlash {
+			if _, values = r.pathValues(&other, withTrailingSlashToggled(req).URL.Path); values != nil {
+				others = append(others, path)
+			}
+		}
+	}
+	return others
+}
+
+// pathValues matches a path against the path of a route, behind the base path of its server
+// It returns the names and values of the path parameters, and nil values when the path does not match
+// The legacy router does not tell the server of its routes, and ignores trailing slashes
+// Their paths are then matched behind the base path of any server of the spec, with or without a trailing slash
+func (r *specRouter) pathValues(route *routers.Route, path string) ([]string, []string) {
+	template := route.Path
+	var anchor string
+	if route.Server != nil {
+		_, basePath := splitServerUrl(route.Server.URL)
+		template = strings.TrimSuffix(basePath, "/") + template
+		anchor = "^"
+	} else {
+		anchor = r.basePathsPattern()
+	}
+
+	compiled, ok := r.templates[anchor+template]
+	if !ok {
+		expression := anchor
+		if r.options.CaseInsensitive {
+			expression = "(?i)" + anchor
+		}
+		rest := template
+		for {
+			start := strings.Index(rest, "{")
+			end := strings.Index(rest, "}")
+			if start < 0 || end < start {
+				break
+			}
+			expression += regexp.QuoteMeta(rest[:start]) + "([^/]+)"
+			compiled.names = append(compiled.names, rest[start+1:end])
+			rest = rest[end+1:]
+		}
+		if route.Server == nil {
+			expression += regexp.QuoteMeta(strings.TrimSuffix(rest, "/")) + "/?$"
+		} else {
+			expression += regexp.QuoteMeta(rest) + "$"
+		}
+		compiled.pattern = regexp.MustCompile(expression)
+		r.templates[anchor+template] = compiled
+	}
+
+	matches := compiled.pattern.FindStringSubmatch(path)
+	if matches == nil {
+		return nil, nil
+	}
+	return compiled.names, matches[1:]
+}
+
+// basePathsPattern matches the start of a path up to the base path of any server of the spec, the variables of which match any segment
+func (r *specRouter) basePathsPattern() string {
+	if len(r.doc.Servers) == 0 {
+		return "^"
+	}
+	var basePaths []string
+	for _, server := range r.doc.Servers {
+		_, basePath := splitServerUrl(server.URL)
+		expression := ""
+		rest := strings.TrimSuffix(basePath, "/")
+		for {
+			start := strings.Index(rest, "{")
+			end := strings.Index(rest, "}")
+			if start < 0 || end < start {
+				break
+			}
+			expression += regexp.QuoteMeta(rest[:start]) + "[^/]+"
+			rest = rest[end+1:]
+		}
+		basePaths = append(basePaths, expression+regexp.QuoteMeta(rest))
+	}
+	return "^(?:" + strings.Join(basePaths, "|") + ")"
+}
+
+// lowerServers returns lowercased copies of servers
+func (r *specRouter) lowerServers(servers openapi3.Servers) openapi3.Servers {
+	if servers == nil {
+		return nil
+	}
+	lowered := make(openapi3.Servers, len(servers))
+	for i, server := range servers {
+		loweredServer := *server
+		loweredServer.URL = lowerTemplate(server.URL)
+		r.servers[&loweredServer] = server
+		lowered[i] = &loweredServer
+	}
+	return lowered
+}
+
+// lowerTemplate lowercases a template, except the names of its variables
+func lowerTemplate(template string) string {
+	var builder strings.Builder
+	inVariable := false
+	for _, char := range template {
+		switch char {
+		case '{':
+			inVariable = true
+		case '}':
+			inVariable = false
+		}
+		if inVariable {
+			builder.WriteRune(char)
+		} else {
+			builder.WriteString(strings.ToLower(string(char)))
+		}
+	}
+	return builder.String()
+}
+
+func withTrailingSlashToggled(req *http.Request) *http.Request {
+	toggled := req.Clone(req.Context())
+	if strings.HasSuffix(req.URL.Path, "/") {
+		toggled.URL.Path = strings.TrimSuffix(req.URL.Path, "/")
+	} else {
+		toggled.URL.Path = req.URL.Path + "/"
+	}
+	toggled.URL.RawPath = ""
+	return toggled
+}
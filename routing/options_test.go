@@ -0,0 +1,152 @@
+package routing
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func newOptionsSpec(t *testing.T, options Options) Spec {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.0.3
+info: {title: test, version: "1"}
+servers:
+  - url: https://example.com/Api
+paths:
+  /Users/{userId}:
+    get:
+      parameters:
+        - {name: userId, in: path, required: true, schema: {type: string}}
+      responses:
+        "200": {description: ok}
+  /Users/me:
+    get:
+      responses:
+        "200": {description: ok}
+  /orders/:
+    get:
+      responses:
+        "200": {description: ok}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := Spec{Name: "users"}
+	if spec.Router, err = NewSpecRouter(doc, options); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestOptions(t *testing.T) {
+	scenario := []struct {
+		options Options
+		url     string
+		route   string
+		userId  string
+	}{
+		{Options{}, "https://example.com/Api/Users/Bob", "/Users/{userId}", "Bob"},
+		{Options{}, "https://example.com/api/users/Bob", "", ""},
+		{Options{}, "https://example.com/Api/orders", "", ""},
+		{Options{CaseInsensitive: true}, "https://EXAMPLE.com/api/users/Bob", "/Users/{userId}", "Bob"},
+		{Options{CaseInsensitive: true}, "https://example.com/api/USERS/ME", "/Users/me", ""},
+		{Options{IgnoreTrailingSlash: true}, "https://example.com/Api/orders", "/orders/", ""},
+		{Options{IgnoreTrailingSlash: true}, "https://example.com/Api/Users/Bob/", "/Users/{userId}", "Bob"},
+		{Options{Implementation: RouterLegacy}, "https://example.com/Api/Users/Bob", "/Users/{userId}", "Bob"},
+		{Options{Implementation: RouterLegacy, CaseInsensitive: true, IgnoreTrailingSlash: true}, "https://example.com/api/users/Bob/", "/Users/{userId}", "Bob"},
+	}
+	for _, elem := range scenario {
+		router := NewRouter(newOptionsSpec(t, elem.options))
+		req, _ := http.NewRequest(http.MethodGet, elem.url, nil)
+		match := router.Match(req)
+		if elem.route == "" {
+			if match.Err == nil {
+				t.Fatal(elem.options, elem.url, "should not match", match.Route.Path)
+			}
+			continue
+		}
+		if match.Err != nil || match.Route.Path != elem.route || match.PathParams["userId"] != elem.userId {
+			t.Fatal(elem.options, elem.url, match.Err)
+		}
+		// The spec keeps its case, the router matches lowercased copies, the legacy router does not tell the server
+		if (elem.options.Implementation != RouterLegacy && match.Server != "https://example.com/Api") || match.Route.Spec.Servers[0].URL != "https://example.com/Api" {
+			t.Fatal(elem.options, elem.url, match.Server, match.Route.Spec.Servers[0].URL)
+		}
+		if match.Route.Spec.Paths.Value(elem.route) != match.Route.PathItem {
+			t.Fatal(elem.options, elem.url, "the path item should be the one of the spec")
+		}
+	}
+
+	if _, err := NewSpecRouter(&openapi3.T{Paths: openapi3.NewPaths()}, Options{Implementation: "chi"}); err == nil {
+		t.Fatal("unknown routers should fail")
+	}
+}
+
+func TestOtherRoutes(t *testing.T) {
+	router := NewRouter(newOptionsSpec(t, Options{}))
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/Api/Users/me", nil)
+	match := router.Match(req)
+	if match.Err != nil || match.Route.Path != "/Users/me" || !slices.Equal(match.OtherRoutes, []string{"/Users/{userId}"}) {
+		t.Fatal(match.Err, match.OtherRoutes)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com/Api/Users/Bob", nil)
+	match = router.Match(req)
+	if match.Err != nil || len(match.OtherRoutes) > 0 {
+		t.Fatal(match.Err, match.OtherRoutes)
+	}
+}
+
+func TestOtherRoutesAnchoring(t *testing.T) {
+	scenario := []struct {
+		servers string
+		url     string
+	}{
+		{"servers:\n  - url: https://example.com/{version}\n    variables: {version: {default: v1}}\n", "https://example.com/v1"},
+		{"servers:\n  - url: https://example.com/api\n  - url: https://example.com/v2/api\n", "https://example.com/v2/api"},
+		{"", "http://localhost"},
+	}
+	for _, elem := range scenario {
+		for _, implementation := range []string{RouterGorillaMux, RouterLegacy} {
+			doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.0.3
+info: {title: test, version: "1"}
+` + elem.servers + `paths:
+  /users/{userId}:
+    get:
+      parameters:
+        - {name: userId, in: path, required: true, schema: {type: string}}
+      responses:
+        "200": {description: ok}
+  /users/me:
+    get:
+      responses:
+        "200": {description: ok}
+  /admin/users/me:
+    get:
+      responses:
+        "200": {description: ok}
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec := Spec{Name: "users"}
+			if spec.Router, err = NewSpecRouter(doc, Options{Implementation: implementation}); err != nil {
+				t.Fatal(err)
+			}
+			router := NewRouter(spec)
+
+			// The paths are matched from the base path of the servers, not against the end of the request path
+			req, _ := http.NewRequest(http.MethodGet, elem.url+"/admin/users/me", nil)
+			match := router.Match(req)
+			if match.Err != nil || match.Route.Path != "/admin/users/me" || len(match.OtherRoutes) > 0 {
+				t.Fatal(elem.url, implementation, match.Err, match.OtherRoutes)
+			}
+
+			req, _ = http.NewRequest(http.MethodGet, elem.url+"/users/me", nil)
+			match = router.Match(req)
+			if match.Err != nil || match.Route.Path != "/users/me" || !slices.Equal(match.OtherRoutes, []string{"/users/{userId}"}) {
+				t.Fatal(elem.url, implementation, match.Err, match.OtherRoutes)
+			}
+		}
+	}
+}
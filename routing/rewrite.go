@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"github.com/gobwas/glob"
+	"net"
+	"net/http"
+	"regexp"
+)
+
+// Rewrite adjusts the exchanges before routing, so that URLs recorded behind a gateway line up with the specs
+// Hosts are replaced first, then paths are rewritten, query parameters stripped and headers changed
+type Rewrite struct {
+	// Hosts replace the hosts of the exchanges, they are given with or without their port
+	Hosts map[string]string
+	Paths []PathRewrite
+	// StripQuery removes the query parameters matching one of its globs
+	StripQuery []glob.Glob
+	// SetHeaders are set on every exchange, after RemoveHeaders are removed
+	SetHeaders    map[string]string
+	RemoveHeaders []string
+}
+
+// PathRewrite replaces the paths matching Pattern, Replacement may refer to its groups as $1 or ${name}
+type PathRewrite struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Apply rewrites a request in place, and tells whether its URL changed
+func (r *Rewrite) Apply(req *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	original := req.URL.String()
+
+	if host, ok := r.Hosts[req.URL.Host]; ok {
+		req.URL.Host = host
+		req.Host = host
+	} else if host, ok = r.Hosts[req.URL.Hostname()]; ok {
+		if _, _, err := net.SplitHostPort(host); err != nil && req.URL.Port() != "" {
+			// The port is kept when the replacement has none
+			host = net.JoinHostPort(host, req.URL.Port())
+		}
+		req.URL.Host = host
+		req.Host = host
+	}
+
+	for _, rule := range r.Paths {
+		if rule.Pattern.MatchString(req.URL.Path) {
+			req.URL.Path = rule.Pattern.ReplaceAllString(req.URL.Path, rule.Replacement)
+			req.URL.RawPath = ""
+		}
+	}
+
+	if len(r.StripQuery) > 0 && req.URL.RawQuery != "" {
+		query := req.URL.Query()
+		for name := range query {
+			for _, stripped := range r.StripQuery {
+				if stripped.Match(name) {
+					query.Del(name)
+					break
+				}
+			}
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	for _, name := range r.RemoveHeaders {
+		req.Header.Del(name)
+	}
+	for name, value := range r.SetHeaders {
+		req.Header.Set(name, value)
+	}
+	return req.URL.String() != original
+}
@@ -0,0 +1,41 @@
+package routing
+
+import (
+	"github.com/gobwas/glob"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	rewrite := &Rewrite{
+		Hosts: map[string]string{"gateway.internal": "api.example.com"},
+		Paths: []PathRewrite{
+			{Pattern: regexp.MustCompile(`^/gateway(/.*)$`), Replacement: "$1"},
+			{Pattern: regexp.MustCompile(`^/v1/`), Replacement: "/v2/"},
+		},
+		StripQuery:    []glob.Glob{glob.MustCompile("utm_*"), glob.MustCompile("apiKey")},
+		SetHeaders:    map[string]string{"Api-Version": "2"},
+		RemoveHeaders: []string{"X-Gateway-Trace"},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://gateway.internal:8443/gateway/v1/users?apiKey=secret&limit=2&utm_source=ci", nil)
+	req.Header.Set("X-Gateway-Trace", "abc")
+	if !rewrite.Apply(req) {
+		t.Fatal("the URL should be rewritten")
+	}
+	if req.URL.String() != "https://api.example.com:8443/v2/users?limit=2" {
+		t.Fatal(req.URL)
+	}
+	if req.Header.Get("Api-Version") != "2" || req.Header.Get("X-Gateway-Trace") != "" {
+		t.Fatal(req.Header)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "https://other/users", nil)
+	if rewrite.Apply(req) || req.URL.String() != "https://other/users" {
+		t.Fatal("unmatched URLs should be kept", req.URL)
+	}
+	if (*Rewrite)(nil).Apply(req) {
+		t.Fatal("no rewrite should change nothing")
+	}
+}
@@ -0,0 +1,141 @@
+package routing
+
+import (
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gobwas/glob"
+	"net/http"
+	"net/url"
+	"static-openapivalidator/validator"
+	"strings"
+)
+
+// ErrNoSpec is returned for exchanges that are bound to none of the specs
+var ErrNoSpec = errors.New("no spec matches the exchange")
+
+// Spec is a loaded spec, along with the exchanges it is bound to
+// A spec without any binding accepts every exchange
+type Spec struct {
+	Name   string
+	Router routers.Router
+	// Host is matched against the host of the exchange, with or without its port
+	Host glob.Glob
+	// Prefix restricts the spec to the paths starting with it, it is removed before routing when Strip is set
+	Prefix string
+	Strip  bool
+	// HeaderValue is matched against the value of the HeaderName header of the exchange
+	HeaderName  string
+	HeaderValue glob.Glob
+	// BodyValidator validates the bodies of the exchanges in place of kin-openapi when set
+	BodyValidator validator.BodyValidator
+	// Locations maps the locations of the errors back to the original spec, when the spec was converted
+	Locations *strings.Replacer
+	// ServerURLs are the URLs the servers of the router stand for, as returned by PrepareServers
+	ServerURLs map[*openapi3.Server]string
+	// Hosts replace the hosts of the exchanges before routing, they are given with or without their port
+	Hosts map[string]*url.URL
+}
+
+// Router routes every exchange to the router of the spec it is bound to
+type Router struct {
+	Specs []Spec
+	// Rewrite is applied to the exchanges before they are routed, when set
+	Rewrite *Rewrite
+}
+
+// Match is the outcome of routing an exchange
+type Match struct {
+	Spec       string
+	Route      *routers.Route
+	PathParams map[string]string
+	// Server is the URL of the server matched, with the values of its variables
+	Server string
+	// BodyValidator and Locations are the ones of the spec
+	BodyValidator validator.BodyValidator
+	Locations     *strings.Replacer
+	// OtherRoutes are the other paths of the spec matching the exchange, Route was preferred to them
+	OtherRoutes []string
+	// Err is set when no route was found, it wraps ErrNoSpec when no spec is bound to the exchange
+	Err error
+}
+
+func NewRouter(specs ...Spec) *Router {
+	return &Router{Specs: specs}
+}
+
+// Match finds the route of an exchange
+// When several specs are bound to the exchange, the first one with a route for it is used
+func (r *Router) Match(req *http.Request) Match {
+	var candidates []Match
+	for _, spec := range r.Specs {
+		if !spec.binds(req) {
+			continue
+		}
+		routed := spec.routedRequest(req)
+		route, pathParams, err := spec.Router.FindRoute(routed)
+		match := Match{Spec: spec.Name, Route: route, PathParams: pathParams, BodyValidator: spec.BodyValidator, Locations: spec.Locations, Err: err}
+		if err == nil {
+			match.Server = spec.serverUrl(route.Server, pathParams)
+			if ambiguous, ok := spec.Router.(interface {
+				OtherRoutes(req *http.Request, route *routers.Route) []string
+			}); ok {
+				match.OtherRoutes = ambiguous.OtherRoutes(routed, route)
+			}
+			return match
+		}
+		candidates = append(candidates, match)
+	}
+
+	if len(candidates) == 0 {
+		return Match{Err: ErrNoSpec}
+	}
+	// A bad method is more telling than a missing path
+	for _, candidate := range candidates {
+		if errors.Is(candidate.Err, routers.ErrMethodNotAllowed) {
+			return candidate
+		}
+	}
+	return candidates[0]
+}
+
+func (s Spec) binds(req *http.Request) bool {
+	if s.Host != nil && !s.Host.Match(req.URL.Host) && !s.Host.Match(req.URL.Hostname()) {
+		return false
+	}
+	if s.Prefix != "" && req.URL.Path != s.Prefix && !strings.HasPrefix(req.URL.Path, strings.TrimSuffix(s.Prefix, "/")+"/") {
+		return false
+	}
+	if s.HeaderName != "" && (s.HeaderValue == nil || !s.HeaderValue.Match(req.Header.Get(s.HeaderName))) {
+		return false
+	}
+	return true
+}
+
+// routedRequest returns the request as seen by the spec, without its prefix when it is stripped, and on the host it stands for
+func (s Spec) routedRequest(req *http.Request) *http.Request {
+	target, mapped := s.Hosts[req.URL.Host]
+	if !mapped {
+		target, mapped = s.Hosts[req.URL.Hostname()]
+	}
+	stripped := s.Strip && s.Prefix != ""
+	if !stripped && !mapped {
+		return req
+	}
+
+	routed := req.Clone(req.Context())
+	if stripped {
+		routed.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(s.Prefix, "/")), "/")
+		routed.URL.RawPath = ""
+	}
+	if mapped {
+		routed.URL.Scheme = target.Scheme
+		routed.URL.Host = target.Host
+		routed.Host = target.Host
+		if basePath := strings.TrimSuffix(target.Path, "/"); basePath != "" {
+			routed.URL.Path = basePath + routed.URL.Path
+			routed.URL.RawPath = ""
+		}
+	}
+	return routed
+}
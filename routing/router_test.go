@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gobwas/glob"
+	"net/http"
+	"testing"
+)
+
+func newSpecRouter(t *testing.T, path string) routers.Router {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.0.3
+info: {title: test, version: "1"}
+paths:
+  ` + path + `:
+    get:
+      responses:
+        "200": {description: ok}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func TestMatch(t *testing.T) {
+	router := NewRouter(
+		Spec{Name: "users", Router: newSpecRouter(t, "/users/{id}"), Prefix: "/users-service", Strip: true},
+		Spec{Name: "orders-v2", Router: newSpecRouter(t, "/orders"), HeaderName: "Api-Version", HeaderValue: glob.MustCompile("2*")},
+		Spec{Name: "orders", Router: newSpecRouter(t, "/orders"), Host: glob.MustCompile("orders.*")},
+	)
+	scenario := []struct {
+		url     string
+		version string
+		spec    string
+		err     error
+	}{
+		{"http://gateway/users-service/users/1", "", "users", nil},
+		{"http://gateway/users-service/orders", "", "users", routers.ErrPathNotFound},
+		{"http://orders.local:8080/orders", "2.1", "orders-v2", nil},
+		{"http://orders.local:8080/orders", "", "orders", nil},
+		{"http://gateway/orders", "", "", ErrNoSpec},
+	}
+	for _, elem := range scenario {
+		req, _ := http.NewRequest(http.MethodGet, elem.url, nil)
+		if elem.version != "" {
+			req.Header.Set("Api-Version", elem.version)
+		}
+		path := req.URL.Path
+		match := router.Match(req)
+		if match.Spec != elem.spec {
+			t.Fatal(elem.url, match.Spec)
+		}
+		if (elem.err == nil) != (match.Err == nil) || (elem.err != nil && !errors.Is(match.Err, elem.err)) {
+			t.Fatal(elem.url, match.Err)
+		}
+		if req.URL.Path != path {
+			t.Fatal("the exchange should not be modified", req.URL.Path)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package routing
+
+import (
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Server matching modes
+const (
+	// ServersExact matches the scheme, host and base path of the servers
+	ServersExact = "exact"
+	// ServersAnyHost matches the base path of the servers, on any scheme and host
+	ServersAnyHost = "any-host"
+	// ServersMap replaces the hosts of the exchanges with the ones they stand for, then matches the servers exactly
+	ServersMap = "map"
+)
+
+// PrepareServers rewrites the servers of a spec for the matching mode
+// Servers are expanded for every value of their enumerated variables, so that only these values match
+// It returns the URL each rewritten server stands for
+func PrepareServers(doc *openapi3.T, mode string) (map[*openapi3.Server]string, error) {
+	if mode != "" && mode != ServersExact && mode != ServersAnyHost && mode != ServersMap {
+		return nil, fmt.Errorf("invalid servers mode %q, expected %s, %s or %s", mode, ServersExact, ServersAnyHost, ServersMap)
+	}
+	urls := make(map[*openapi3.Server]string)
+	doc.Servers = prepareServers(doc.Servers, mode, urls)
+	for _, pathItem := range doc.Paths.Map() {
+		if len(pathItem.Servers) > 0 {
+			pathItem.Servers = prepareServers(pathItem.Servers, mode, urls)
+		}
+	}
+	return urls, nil
+}
+
+func prepareServers(servers openapi3.Servers, mode string, urls map[*openapi3.Server]string) openapi3.Servers {
+	var prepared openapi3.Servers
+	for _, server := range servers {
+		origin := ""
+		if mode == ServersAnyHost {
+			// The variables of the host are left as is, any host matching
+			var path string
+			origin, path = splitServerUrl(server.URL)
+			server = &openapi3.Server{URL: path, Description: server.Description, Variables: server.Variables}
+		}
+		for _, expanded := range expandServer(server) {
+			urls[expanded] = origin + expanded.URL
+			prepared = append(prepared, expanded)
+		}
+	}
+	return prepared
+}
+
+// expandServer returns a server for every combination of the values of its enumerated variables
+// The other variables are kept, and match any value
+func expandServer(server *openapi3.Server) []*openapi3.Server {
+	var names []string
+	for name, variable := range server.Variables {
+		if len(variable.Enum) > 0 && strings.Contains(server.URL, "{"+name+"}") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	expanded := []*openapi3.Server{{URL: server.URL, Description: server.Description, Variables: make(map[string]*openapi3.ServerVariable)}}
+	for name, variable := range server.Variables {
+		if !slices.Contains(names, name) {
+			expanded[0].Variables[name] = variable
+		}
+	}
+	for _, name := range names {
+		var next []*openapi3.Server
+		for _, partial := range expanded {
+			for _, value := range server.Variables[name].Enum {
+				next = append(next, &openapi3.Server{
+					URL:         strings.ReplaceAll(partial.URL, "{"+name+"}", value),
+					Description: partial.Description,
+					Variables:   partial.Variables,
+				})
+			}
+		}
+		expanded = next
+	}
+	return expanded
+}
+
+// splitServerUrl splits a server URL into its scheme and host, and its path
+func splitServerUrl(serverUrl string) (string, string) {
+	scheme, rest, found := strings.Cut(serverUrl, "://")
+	if !found {
+		return "", serverUrl
+	}
+	if index := strings.Index(rest, "/"); index >= 0 {
+		return scheme + "://" + rest[:index], rest[index:]
+	}
+	return serverUrl, "/"
+}
+
+// ParseHosts parses a mapping of the hosts of the exchanges to the URLs they stand for
+// A path in a URL is added in front of the paths of the exchanges
+func ParseHosts(hosts map[string]string) (map[string]*url.URL, error) {
+	parsed := make(map[string]*url.URL)
+	for host, target := range hosts {
+		targetUrl, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if targetUrl.Scheme == "" || targetUrl.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q for host %s, expected scheme://host", target, host)
+		}
+		parsed[host] = targetUrl
+	}
+	return parsed, nil
+}
+
+// serverUrl returns the URL of the server matched by a route, with the values of its variables
+func (s Spec) serverUrl(server *openapi3.Server, pathParams map[string]string) string {
+	if server == nil {
+		return ""
+	}
+	serverUrl, ok := s.ServerURLs[server]
+	if !ok {
+		serverUrl = server.URL
+	}
+	for name := range server.Variables {
+		if value, ok := pathParams[name]; ok {
+			serverUrl = strings.ReplaceAll(serverUrl, "{"+name+"}", value)
+		}
+	}
+	return serverUrl
+}
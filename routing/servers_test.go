@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"net/http"
+	"testing"
+)
+
+func newServersSpec(t *testing.T, mode string, hosts map[string]string) Spec {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`openapi: 3.0.3
+info: {title: test, version: "1"}
+servers:
+  - url: https://{region}.example.com/api/{version}
+    variables:
+      region: {default: eu, enum: [eu, us]}
+      version: {default: v1}
+paths:
+  /users:
+    get:
+      responses:
+        "200": {description: ok}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := Spec{Name: "users"}
+	if spec.ServerURLs, err = PrepareServers(doc, mode); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Hosts, err = ParseHosts(hosts); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Router, err = gorillamux.NewRouter(doc); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestServers(t *testing.T) {
+	scenario := []struct {
+		mode   string
+		hosts  map[string]string
+		url    string
+		server string
+	}{
+		{ServersExact, nil, "https://us.example.com/api/v2/users", "https://us.example.com/api/v2"},
+		{ServersExact, nil, "https://asia.example.com/api/v2/users", ""},
+		{ServersExact, nil, "http://localhost:8080/api/v2/users", ""},
+		{ServersAnyHost, nil, "http://localhost:8080/api/v2/users", "https://{region}.example.com/api/v2"},
+		{ServersAnyHost, nil, "http://localhost:8080/users", ""},
+		{ServersMap, map[string]string{"localhost:8080": "https://us.example.com/api"}, "http://localhost:8080/v1/users", "https://us.example.com/api/v1"},
+		{ServersMap, map[string]string{"localhost": "https://us.example.com"}, "http://localhost:3000/api/v1/users", "https://us.example.com/api/v1"},
+		{ServersMap, map[string]string{"localhost": "https://us.example.com"}, "http://127.0.0.1/api/v1/users", ""},
+	}
+	for _, elem := range scenario {
+		router := NewRouter(newServersSpec(t, elem.mode, elem.hosts))
+		req, _ := http.NewRequest(http.MethodGet, elem.url, nil)
+		match := router.Match(req)
+		if (match.Err == nil) != (elem.server != "") || match.Server != elem.server {
+			t.Fatal(elem.mode, elem.url, match.Server, match.Err)
+		}
+		if req.URL.String() != elem.url {
+			t.Fatal("the exchange should not be changed", req.URL)
+		}
+	}
+
+	if _, err := PrepareServers(&openapi3.T{}, "none"); err == nil {
+		t.Fatal("unknown modes should fail")
+	}
+}
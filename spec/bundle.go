@@ -0,0 +1,125 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"strings"
+)
+
+// Source is the file and JSON pointer a part of a spec was loaded from
+type Source struct {
+	File    string `json:"file"`
+	Pointer string `json:"pointer"`
+}
+
+func (s Source) String() string {
+	return s.File + "#" + s.Pointer
+}
+
+// SourceMap maps the components of a bundled spec, such as #/components/schemas/User, to their sources
+type SourceMap map[string]Source
+
+// SourceOf returns where the target of a reference was loaded from
+func SourceOf(ref openapi3.ComponentRef) (Source, bool) {
+	refPath := ref.RefPath()
+	if ref.RefString() == "" || refPath == nil {
+		return Source{}, false
+	}
+	pointer := refPath.Fragment
+	file := *refPath
+	file.Fragment = ""
+	file.RawFragment = ""
+	return Source{File: file.String(), Pointer: pointer}, true
+}
+
+// Bundle moves the external components of a spec loaded from root into its own components, so that its $refs are all internal
+// It returns where every component of the bundled spec comes from
+func Bundle(ctx context.Context, doc *openapi3.T, root string) SourceMap {
+	sources := make(SourceMap)
+	if doc.Components != nil {
+		addSources(sources, root, "schemas", doc.Components.Schemas)
+		addSources(sources, root, "parameters", doc.Components.Parameters)
+		addSources(sources, root, "headers", doc.Components.Headers)
+		addSources(sources, root, "requestBodies", doc.Components.RequestBodies)
+		addSources(sources, root, "responses", doc.Components.Responses)
+		addSources(sources, root, "securitySchemes", doc.Components.SecuritySchemes)
+		addSources(sources, root, "examples", doc.Components.Examples)
+		addSources(sources, root, "links", doc.Components.Links)
+		addSources(sources, root, "callbacks", doc.Components.Callbacks)
+	}
+
+	doc.InternalizeRefs(ctx, func(doc *openapi3.T, ref openapi3.ComponentRef) string {
+		name := openapi3.DefaultRefNameResolver(doc, ref)
+		component := "#/components/" + ref.CollectionName() + "/" + name
+		if _, ok := sources[component]; !ok {
+			if source, ok := SourceOf(ref); ok {
+				sources[component] = source
+			}
+		}
+		return name
+	})
+	return sources
+}
+
+// addSources adds the components of the root document to a source map
+func addSources[R openapi3.ComponentRef](sources SourceMap, root string, collection string, components map[string]R) {
+	for name, component := range components {
+		pointer := encodePointer([]string{"components", collection, name})
+		source, ok := SourceOf(component)
+		if !ok {
+			source = Source{File: root, Pointer: pointer}
+		}
+		sources["#"+pointer] = source
+	}
+}
+
+// Dereference returns a bundled spec as JSON, with every $ref replaced by its target
+// Circular references cannot be replaced, they are kept along with the components they refer to
+func Dereference(doc *openapi3.T) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var document any
+	if err = json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(dereference(document, document, nil))
+}
+
+func dereference(root any, value any, visiting []string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		if ref, ok := typed["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+			for _, visited := range visiting {
+				if visited == ref {
+					return typed
+				}
+			}
+			pointer, err := url.PathUnescape(strings.TrimPrefix(ref, "#"))
+			if err != nil {
+				return typed
+			}
+			target, found := walkPointer(root, pointer)
+			if !found {
+				return typed
+			}
+			return dereference(root, target, append(visiting, ref))
+		}
+		result := make(map[string]any, len(typed))
+		for key, child := range typed {
+			result[key] = dereference(root, child, visiting)
+		}
+		return result
+	case []any:
+		result := make([]any, len(typed))
+		for i, child := range typed {
+			result[i] = dereference(root, child, visiting)
+		}
+		return result
+	default:
+		return value
+	}
+}
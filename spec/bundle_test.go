@@ -0,0 +1,108 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const bundledSpec = `openapi: 3.0.3
+info: {title: bundled, version: "1"}
+paths:
+  /users:
+    get:
+      parameters:
+        - $ref: "#/components/parameters/Limit"
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: "schemas/user.yaml"
+components:
+  parameters:
+    Limit: {name: limit, in: query, schema: {type: integer}}
+`
+
+const bundledUser = `type: object
+properties:
+  manager:
+    $ref: "user.yaml"
+  team:
+    $ref: "common.yaml#/Team"
+`
+
+const bundledCommon = `Team:
+  type: object
+  properties:
+    name: {type: string}
+`
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"openapi.yaml":        bundledSpec,
+		"schemas/user.yaml":   bundledUser,
+		"schemas/common.yaml": bundledCommon,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.ToSlash(filepath.Join(dir, "openapi.yaml"))
+	doc, err := (&openapi3.Loader{IsExternalRefsAllowed: true}).LoadFromFile(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := Bundle(context.Background(), doc, root)
+	expected := map[string]string{
+		"#/components/parameters/Limit":            root + "#/components/parameters/Limit",
+		"#/components/schemas/schemas_user":        filepath.ToSlash(filepath.Join(dir, "schemas/user.yaml")) + "#",
+		"#/components/schemas/schemas_common_Team": filepath.ToSlash(filepath.Join(dir, "schemas/common.yaml")) + "#/Team",
+	}
+	if len(sources) != len(expected) {
+		t.Fatal(sources)
+	}
+	for component, source := range expected {
+		if sources[component].String() != source {
+			t.Fatal(component, sources[component])
+		}
+	}
+
+	bundled, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bundled), ".yaml") {
+		t.Fatal("every $ref should be internal", string(bundled))
+	}
+
+	dereferenced, err := Dereference(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]any
+	if err = json.Unmarshal(dereferenced, &document); err != nil {
+		t.Fatal(err)
+	}
+	schema, _ := walkPointer(document, "/paths/~1users/get/responses/200/content/application~1json/schema")
+	team, _ := walkPointer(schema, "/properties/team/properties/name/type")
+	manager, _ := walkPointer(schema, "/properties/manager/$ref")
+	if team != "string" || manager != "#/components/schemas/schemas_user" {
+		t.Fatal("only circular references should be kept", team, manager)
+	}
+	if parameter, _ := walkPointer(document, "/paths/~1users/get/parameters/0/name"); parameter != "limit" {
+		t.Fatal(parameter)
+	}
+}
@@ -0,0 +1,631 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Version is a loaded version of a spec, to compare with Diff
+type Version struct {
+	Doc *openapi3.T
+	// File is the location the spec was loaded from
+	File string
+	// Locations maps the locations of a converted spec back to the ones of the original spec, when set
+	Locations *strings.Replacer
+}
+
+// Change is a difference between two versions of a spec
+type Change struct {
+	// Id names the kind of change, such as removed-operation or request-narrowed-enum
+	Id       string `json:"id"`
+	Breaking bool   `json:"breaking"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	// Status is the response the change is in, such as 200, 2XX or default, it is empty for the changes of requests
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message"`
+	// Location is in the base spec for removals, in the revision otherwise
+	Location Source `json:"location"`
+	// Exchanges counts the recorded exchanges of the operation and status of the change, when reports are given
+	Exchanges int `json:"exchanges,omitempty"`
+}
+
+func (c Change) String() string {
+	operation := c.Method + " " + c.Path
+	if c.Status != "" {
+		operation += " " + c.Status
+	}
+	return fmt.Sprintf("%s %s: %s (%s)", c.Id, operation, c.Message, c.Location)
+}
+
+// Diff lists the changes from a base version of a spec to a revision, and tells the breaking ones
+// A change is breaking when clients of the base version may fail against the revision
+func Diff(base Version, revision Version) []Change {
+	d := &differ{base: base, revision: revision, reported: make(map[string]bool)}
+	at := position{base: Source{File: base.File}, revision: Source{File: revision.File}}
+
+	revisionPaths := make(map[string]string)
+	for _, path := range sortedKeys(revision.Doc.Paths.Map()) {
+		revisionPaths[normalizePath(path)] = path
+	}
+	basePaths := make(map[string]bool)
+	for _, path := range sortedKeys(base.Doc.Paths.Map()) {
+		basePaths[normalizePath(path)] = true
+		baseItem := base.Doc.Paths.Value(path)
+		var revisionItem *openapi3.PathItem
+		revisionPath, found := revisionPaths[normalizePath(path)]
+		if found {
+			revisionItem = revision.Doc.Paths.Value(revisionPath)
+		}
+		pathAt := position{base: at.base.child("paths", path), revision: at.revision.child("paths", revisionPath)}
+
+		baseOperations := baseItem.Operations()
+		for _, method := range sortedKeys(baseOperations) {
+			operation := operation{method: method, path: path}
+			operationAt := pathAt.child(strings.ToLower(method))
+			var revisionOperation *openapi3.Operation
+			if revisionItem != nil {
+				revisionOperation = revisionItem.GetOperation(method)
+			}
+			if revisionOperation == nil {
+				d.add(operation, "removed-operation", true, operationAt.base, "the operation was removed")
+				continue
+			}
+			d.compareOperation(operation, revisionPath, baseItem, revisionItem, baseOperations[method], revisionOperation, pathAt, operationAt)
+		}
+		if revisionItem != nil {
+			for _, method := range sortedKeys(revisionItem.Operations()) {
+				if baseOperations[method] == nil {
+					d.add(operation{method: method, path: revisionPath}, "added-operation", false, pathAt.revision.child(strings.ToLower(method)), "the operation was added")
+				}
+			}
+		}
+	}
+	for _, path := range sortedKeys(revision.Doc.Paths.Map()) {
+		if basePaths[normalizePath(path)] {
+			continue
+		}
+		for _, method := range sortedKeys(revision.Doc.Paths.Value(path).Operations()) {
+			d.add(operation{method: method, path: path}, "added-operation", false, at.revision.child("paths", path, strings.ToLower(method)), "the operation was added")
+		}
+	}
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Breaking && !d.changes[j].Breaking
+	})
+	return d.changes
+}
+
+type differ struct {
+	base     Version
+	revision Version
+	changes  []Change
+	reported map[string]bool
+}
+
+// operation is the operation, and response when set, the changes are in
+type operation struct {
+	method string
+	path   string
+	status string
+}
+
+// position is where the compared parts are, in the base spec and in the revision
+type position struct {
+	base     Source
+	revision Source
+}
+
+func (s Source) child(tokens ...string) Source {
+	s.Pointer += encodePointer(tokens)
+	return s
+}
+
+func (p position) child(tokens ...string) position {
+	return position{base: p.base.child(tokens...), revision: p.revision.child(tokens...)}
+}
+
+// follow moves to the targets of the compared parts when they are $refs
+func follow[R openapi3.ComponentRef](p position, base R, revision R) position {
+	if source, ok := SourceOf(base); ok {
+		p.base = source
+	}
+	if source, ok := SourceOf(revision); ok {
+		p.revision = source
+	}
+	return p
+}
+
+func (d *differ) add(operation operation, id string, breaking bool, location Source, message string, args ...any) {
+	locations := d.revision.Locations
+	if location.File == d.base.File {
+		locations = d.base.Locations
+	}
+	if locations != nil {
+		location.Pointer = strings.TrimPrefix(locations.Replace("#"+location.Pointer), "#")
+	}
+	// A schema reached twice, such as a recursive one, is reported once by operation
+	key := fmt.Sprint(id, operation, location)
+	if d.reported[key] {
+		return
+	}
+	d.reported[key] = true
+	d.changes = append(d.changes, Change{
+		Id:       id,
+		Breaking: breaking,
+		Method:   operation.method,
+		Path:     operation.path,
+		Status:   operation.status,
+		Message:  fmt.Sprintf(message, args...),
+		Location: location,
+	})
+}
+
+func (d *differ) compareOperation(operation operation, revisionPath string, baseItem, revisionItem *openapi3.PathItem, base, revision *openapi3.Operation, pathAt, at position) {
+	baseParameters := parameters(operation.path, baseItem, base, pathAt.base, at.base)
+	revisionParameters := parameters(revisionPath, revisionItem, revision, pathAt.revision, at.revision)
+	for _, key := range sortedKeys(baseParameters) {
+		baseParameter := baseParameters[key]
+		revisionParameter, found := revisionParameters[key]
+		name := fmt.Sprintf("%s parameter %q", baseParameter.value.In, baseParameter.value.Name)
+		if !found {
+			d.add(operation, "removed-parameter", false, baseParameter.source, "%s was removed", name)
+			continue
+		}
+		if !baseParameter.value.Required && revisionParameter.value.Required {
+			d.add(operation, "parameter-became-required", true, revisionParameter.source, "%s became required", name)
+		} else if baseParameter.value.Required && !revisionParameter.value.Required {
+			d.add(operation, "parameter-became-optional", false, revisionParameter.source, "%s became optional", name)
+		}
+		if baseParameter.value.Schema != nil && revisionParameter.value.Schema != nil {
+			parameterAt := follow(position{base: baseParameter.source, revision: revisionParameter.source}.child("schema"), baseParameter.value.Schema, revisionParameter.value.Schema)
+			d.compareSchema(operation, "request", name, "", baseParameter.value.Schema, revisionParameter.value.Schema, parameterAt, make(map[[2]*openapi3.Schema]bool))
+		}
+	}
+	for _, key := range sortedKeys(revisionParameters) {
+		if _, found := baseParameters[key]; found {
+			continue
+		}
+		revisionParameter := revisionParameters[key]
+		name := fmt.Sprintf("%s parameter %q", revisionParameter.value.In, revisionParameter.value.Name)
+		if revisionParameter.value.Required {
+			d.add(operation, "added-required-parameter", true, revisionParameter.source, "%s was added as required", name)
+		} else {
+			d.add(operation, "added-optional-parameter", false, revisionParameter.source, "%s was added as optional", name)
+		}
+	}
+
+	d.compareRequestBody(operation, base.RequestBody, revision.RequestBody, at.child("requestBody"))
+	d.compareResponses(operation, base.Responses, revision.Responses, at.child("responses"))
+}
+
+type parameter struct {
+	value  *openapi3.Parameter
+	source Source
+}
+
+// parameters lists the parameters of an operation by location and name, those of the operation overriding those of its path
+// The path parameters are listed by position in the path instead, as renaming them changes nothing for the clients
+func parameters(path string, item *openapi3.PathItem, operation *openapi3.Operation, pathSource Source, operationSource Source) map[string]parameter {
+	positions := make(map[string]int)
+	for i, name := range pathParameter.FindAllString(path, -1) {
+		positions[strings.Trim(name, "{}")] = i
+	}
+	found := make(map[string]parameter)
+	addParameters := func(refs openapi3.Parameters, source Source) {
+		for i, ref := range refs {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			location, ok := SourceOf(ref)
+			if !ok {
+				location = source.child("parameters", fmt.Sprint(i))
+			}
+			key := ref.Value.In + " " + ref.Value.Name
+			if position, ok := positions[ref.Value.Name]; ok && ref.Value.In == openapi3.ParameterInPath {
+				key = fmt.Sprintf("%s #%d", ref.Value.In, position)
+			}
+			found[key] = parameter{value: ref.Value, source: location}
+		}
+	}
+	addParameters(item.Parameters, pathSource)
+	addParameters(operation.Parameters, operationSource)
+	return found
+}
+
+func (d *differ) compareRequestBody(operation operation, base, revision *openapi3.RequestBodyRef, at position) {
+	switch {
+	case base == nil && revision == nil:
+		return
+	case base == nil:
+		required := revision.Value != nil && revision.Value.Required
+		if required {
+			d.add(operation, "added-required-request-body", true, at.revision, "a required request body was added")
+		} else {
+			d.add(operation, "added-request-body", false, at.revision, "an optional request body was added")
+		}
+		return
+	case revision == nil:
+		d.add(operation, "removed-request-body", false, at.base, "the request body was removed")
+		return
+	case base.Value == nil || revision.Value == nil:
+		return
+	}
+	at = follow(at, base, revision)
+	if !base.Value.Required && revision.Value.Required {
+		d.add(operation, "request-body-became-required", true, at.revision, "the request body became required")
+	} else if base.Value.Required && !revision.Value.Required {
+		d.add(operation, "request-body-became-optional", false, at.revision, "the request body became optional")
+	}
+	d.compareContent(operation, "request", base.Value.Content, revision.Value.Content, at.child("content"))
+}
+
+func (d *differ) compareResponses(operation operation, base, revision *openapi3.Responses, at position) {
+	if base == nil || revision == nil {
+		return
+	}
+	for _, status := range sortedKeys(base.Map()) {
+		baseResponse := base.Value(status)
+		revisionResponse := revision.Value(status)
+		responseOperation := operation
+		responseOperation.status = status
+		if revisionResponse == nil {
+			d.add(responseOperation, "removed-response", true, at.base.child(status), "the %s response was removed", status)
+			continue
+		}
+		if baseResponse.Value == nil || revisionResponse.Value == nil {
+			continue
+		}
+		responseAt := follow(at.child(status), baseResponse, revisionResponse)
+		d.compareContent(responseOperation, "response", baseResponse.Value.Content, revisionResponse.Value.Content, responseAt.child("content"))
+	}
+	for _, status := range sortedKeys(revision.Map()) {
+		if base.Value(status) == nil {
+			responseOperation := operation
+			responseOperation.status = status
+			d.add(responseOperation, "added-response", false, at.revision.child(status), "the %s response was added", status)
+		}
+	}
+}
+
+// compareContent compares the media types of a request or response, direction being either request or response
+func (d *differ) compareContent(operation operation, direction string, base, revision openapi3.Content, at position) {
+	for _, mediaType := range sortedKeys(base) {
+		revisionMedia, found := revision[mediaType]
+		if !found {
+			// Clients may keep sending a removed request media type, and keep expecting a removed response one
+			d.add(operation, "removed-"+direction+"-media-type", true, at.base.child(mediaType), "the %s %s body was removed", mediaType, direction)
+			continue
+		}
+		baseMedia := base[mediaType]
+		if baseMedia == nil || revisionMedia == nil || baseMedia.Schema == nil || revisionMedia.Schema == nil {
+			continue
+		}
+		mediaAt := follow(at.child(mediaType, "schema"), baseMedia.Schema, revisionMedia.Schema)
+		subject := fmt.Sprintf("the %s %s body", mediaType, direction)
+		d.compareSchema(operation, direction, subject, "", baseMedia.Schema, revisionMedia.Schema, mediaAt, make(map[[2]*openapi3.Schema]bool))
+	}
+	for _, mediaType := range sortedKeys(revision) {
+		if _, found := base[mediaType]; !found {
+			d.add(operation, "added-"+direction+"-media-type", false, at.revision.child(mediaType), "the %s %s body was added", mediaType, direction)
+		}
+	}
+}
+
+// compareSchema compares the schemas of a request or response, name being the path of the compared property in the subject
+// Narrowing a request schema breaks the clients sending what it no longer accepts, widening a response schema breaks those reading it
+func (d *differ) compareSchema(operation operation, direction string, subject string, name string, base, revision *openapi3.SchemaRef, at position, visited map[[2]*openapi3.Schema]bool) {
+	if base.Value == nil || revision.Value == nil || visited[[2]*openapi3.Schema{base.Value, revision.Value}] {
+		return
+	}
+	visited[[2]*openapi3.Schema{base.Value, revision.Value}] = true
+	request := direction == "request"
+	described := subject
+	if name != "" {
+		described = fmt.Sprintf("%s property %q", subject, name)
+	}
+
+	if base.Value.Type != nil && revision.Value.Type != nil && !slices.Equal(base.Value.Type.Slice(), revision.Value.Type.Slice()) {
+		d.add(operation, direction+"-changed-type", true, at.revision, "%s changed type from %s to %s",
+			described, strings.Join(base.Value.Type.Slice(), "|"), strings.Join(revision.Value.Type.Slice(), "|"))
+	}
+
+	removed, added := enumDifference(base.Value.Enum, revision.Value.Enum)
+	if len(removed) > 0 || (len(base.Value.Enum) == 0 && len(revision.Value.Enum) > 0) {
+		d.add(operation, direction+"-narrowed-enum", request, at.revision, "%s no longer accepts %s", described, enumValues(removed, base.Value.Enum))
+	}
+	if len(added) > 0 && len(base.Value.Enum) > 0 {
+		d.add(operation, direction+"-widened-enum", !request, at.revision, "%s may also be %s", described, strings.Join(added, ", "))
+	} else if len(base.Value.Enum) > 0 && len(revision.Value.Enum) == 0 {
+		d.add(operation, direction+"-widened-enum", !request, at.revision, "%s is no longer an enum", described)
+	}
+
+	if !base.Value.Nullable && revision.Value.Nullable {
+		d.add(operation, direction+"-became-nullable", !request, at.revision, "%s may also be null", described)
+	} else if base.Value.Nullable && !revision.Value.Nullable {
+		d.add(operation, direction+"-became-not-nullable", request, at.revision, "%s no longer accepts null", described)
+	}
+	d.compareConstraints(operation, direction, described, base.Value, revision.Value, at.revision)
+
+	// The properties are compared once the changes of the schema itself are reported
+	var common []string
+	for _, property := range sortedKeys(base.Value.Properties) {
+		propertyName := strings.TrimPrefix(name+"."+property, ".")
+		described := fmt.Sprintf("%s property %q", subject, propertyName)
+		revisionProperty, found := revision.Value.Properties[property]
+		if !found {
+			d.add(operation, direction+"-removed-property", !request, at.base.child("properties", property), "%s was removed", described)
+			continue
+		}
+		baseRequired := slices.Contains(base.Value.Required, property)
+		revisionRequired := slices.Contains(revision.Value.Required, property)
+		if !baseRequired && revisionRequired {
+			d.add(operation, direction+"-property-became-required", request && !readOnly(revisionProperty), at.revision.child("required"), "%s became required", described)
+		} else if baseRequired && !revisionRequired {
+			d.add(operation, direction+"-property-became-optional", !request && !writeOnly(revisionProperty), at.revision.child("required"), "%s became optional", described)
+		}
+		common = append(common, property)
+	}
+	for _, property := range sortedKeys(revision.Value.Properties) {
+		if _, found := base.Value.Properties[property]; found {
+			continue
+		}
+		described := fmt.Sprintf("%s property %q", subject, strings.TrimPrefix(name+"."+property, "."))
+		propertyAt := at.revision.child("properties", property)
+		if slices.Contains(revision.Value.Required, property) {
+			d.add(operation, direction+"-added-required-property", request && !readOnly(revision.Value.Properties[property]), propertyAt, "%s was added as required", described)
+		} else {
+			d.add(operation, direction+"-added-optional-property", false, propertyAt, "%s was added as optional", described)
+		}
+	}
+
+	for _, property := range common {
+		propertyAt := follow(at.child("properties", property), base.Value.Properties[property], revision.Value.Properties[property])
+		d.compareSchema(operation, direction, subject, strings.TrimPrefix(name+"."+property, "."), base.Value.Properties[property], revision.Value.Properties[property], propertyAt, visited)
+	}
+
+	if base.Value.Items != nil && revision.Value.Items != nil {
+		itemsAt := follow(at.child("items"), base.Value.Items, revision.Value.Items)
+		d.compareSchema(operation, direction, subject, name+"[]", base.Value.Items, revision.Value.Items, itemsAt, visited)
+	}
+
+	baseAllowed, baseAdditional := additionalProperties(base.Value)
+	revisionAllowed, revisionAdditional := additionalProperties(revision.Value)
+	additionalAt := at.revision.child("additionalProperties")
+	switch {
+	case baseAllowed && !revisionAllowed:
+		d.add(operation, direction+"-disallowed-additional-properties", request, additionalAt, "%s no longer accepts additional properties", described)
+	case !baseAllowed && revisionAllowed:
+		d.add(operation, direction+"-allowed-additional-properties", !request, additionalAt, "%s may also have additional properties", described)
+	case baseAdditional == nil && revisionAdditional != nil:
+		d.add(operation, direction+"-narrowed-additional-properties", request, additionalAt, "%s only accepts additional properties matching a schema", described)
+	case baseAdditional != nil && revisionAdditional == nil:
+		d.add(operation, direction+"-widened-additional-properties", !request, additionalAt, "%s may have any additional properties", described)
+	case baseAdditional != nil && revisionAdditional != nil:
+		propertiesAt := follow(at.child("additionalProperties"), baseAdditional, revisionAdditional)
+		d.compareSchema(operation, direction, subject, strings.TrimPrefix(name+".*", "."), baseAdditional, revisionAdditional, propertiesAt, visited)
+	}
+
+	d.compareComposition(operation, direction, subject, name, "allOf", base.Value.AllOf, revision.Value.AllOf, at, visited)
+	d.compareComposition(operation, direction, subject, name, "anyOf", base.Value.AnyOf, revision.Value.AnyOf, at, visited)
+	d.compareComposition(operation, direction, subject, name, "oneOf", base.Value.OneOf, revision.Value.OneOf, at, visited)
+}
+
+// compareComposition compares the allOf, anyOf or oneOf schemas of a schema, matched by $ref, then by position for the inline ones
+// An added allOf schema narrows the schema as a removed anyOf or oneOf one does, the other way around widens it
+func (d *differ) compareComposition(operation operation, direction string, subject string, name string, keyword string, base, revision openapi3.SchemaRefs, at position, visited map[[2]*openapi3.Schema]bool) {
+	request := direction == "request"
+	described := subject
+	if name != "" {
+		described = fmt.Sprintf("%s property %q", subject, name)
+	}
+	id := strings.ToLower(keyword)
+
+	matched := make(map[int]int)
+	revisionMatched := make(map[int]bool)
+	for i, baseSchema := range base {
+		for j, revisionSchema := range revision {
+			if !revisionMatched[j] && baseSchema.Ref != "" && baseSchema.Ref == revisionSchema.Ref {
+				matched[i], revisionMatched[j] = j, true
+				break
+			}
+		}
+	}
+	for i, baseSchema := range base {
+		if _, found := matched[i]; found || baseSchema.Ref != "" {
+			continue
+		}
+		for j, revisionSchema := range revision {
+			if !revisionMatched[j] && revisionSchema.Ref == "" {
+				matched[i], revisionMatched[j] = j, true
+				break
+			}
+		}
+	}
+
+	for i := range base {
+		j, found := matched[i]
+		if !found {
+			d.add(operation, direction+"-removed-"+id+"-schema", (keyword == "allOf") != request, at.base.child(keyword, fmt.Sprint(i)), "%s no longer has the %s schema %d", described, keyword, i)
+			continue
+		}
+		memberAt := position{base: at.base.child(keyword, fmt.Sprint(i)), revision: at.revision.child(keyword, fmt.Sprint(j))}
+		memberAt = follow(memberAt, base[i], revision[j])
+		d.compareSchema(operation, direction, subject, name, base[i], revision[j], memberAt, visited)
+	}
+	for j := range revision {
+		if !revisionMatched[j] {
+			d.add(operation, direction+"-added-"+id+"-schema", (keyword == "allOf") == request, at.revision.child(keyword, fmt.Sprint(j)), "%s has a new %s schema", described, keyword)
+		}
+	}
+}
+
+// bound is a lower or upper bound of a schema, such as minimum or maxLength
+type bound struct {
+	value     *float64
+	exclusive bool
+}
+
+// compareConstraints compares the bounds, pattern and format of a schema, narrowing them breaks requests and widening them breaks responses
+func (d *differ) compareConstraints(operation operation, direction string, described string, base, revision *openapi3.Schema, at Source) {
+	bounds := []struct {
+		keyword        string
+		upper          bool
+		base, revision bound
+	}{
+		{"minimum", false, bound{base.Min, base.ExclusiveMin}, bound{revision.Min, revision.ExclusiveMin}},
+		{"maximum", true, bound{base.Max, base.ExclusiveMax}, bound{revision.Max, revision.ExclusiveMax}},
+		{"minLength", false, minLength(base.MinLength), minLength(revision.MinLength)},
+		{"maxLength", true, maxLength(base.MaxLength), maxLength(revision.MaxLength)},
+		{"minItems", false, minLength(base.MinItems), minLength(revision.MinItems)},
+		{"maxItems", true, maxLength(base.MaxItems), maxLength(revision.MaxItems)},
+	}
+	request := direction == "request"
+	for _, elem := range bounds {
+		narrowed, widened := compareBounds(elem.base, elem.revision, elem.upper)
+		id := strings.ToLower(elem.keyword)
+		if narrowed {
+			d.add(operation, direction+"-narrowed-"+id, request, at, "%s %s changed from %s to %s", described, elem.keyword, elem.base, elem.revision)
+		} else if widened {
+			d.add(operation, direction+"-widened-"+id, !request, at, "%s %s changed from %s to %s", described, elem.keyword, elem.base, elem.revision)
+		}
+	}
+
+	for _, elem := range []struct{ keyword, base, revision string }{
+		{"pattern", base.Pattern, revision.Pattern},
+		{"format", base.Format, revision.Format},
+	} {
+		switch {
+		case elem.base == elem.revision:
+		case elem.base == "":
+			d.add(operation, direction+"-added-"+elem.keyword, request, at, "%s now has the %s %q", described, elem.keyword, elem.revision)
+		case elem.revision == "":
+			d.add(operation, direction+"-removed-"+elem.keyword, !request, at, "%s no longer has the %s %q", described, elem.keyword, elem.base)
+		default:
+			// Whether the new pattern or format accepts more or less is not known, it may break either side
+			d.add(operation, direction+"-changed-"+elem.keyword, true, at, "%s %s changed from %q to %q", described, elem.keyword, elem.base, elem.revision)
+		}
+	}
+}
+
+// compareBounds tells whether the revision bound accepts fewer values than the base one, or more
+func compareBounds(base, revision bound, upper bool) (bool, bool) {
+	switch {
+	case base.value == nil && revision.value == nil:
+		return false, false
+	case base.value == nil:
+		return true, false
+	case revision.value == nil:
+		return false, true
+	case *base.value == *revision.value:
+		return !base.exclusive && revision.exclusive, base.exclusive && !revision.exclusive
+	case upper:
+		return *revision.value < *base.value, *revision.value > *base.value
+	default:
+		return *revision.value > *base.value, *revision.value < *base.value
+	}
+}
+
+// minLength is the lower bound of a length or count, 0 being no bound
+func minLength(length uint64) bound {
+	if length == 0 {
+		return bound{}
+	}
+	value := float64(length)
+	return bound{value: &value}
+}
+
+// maxLength is the upper bound of a length or count, when set
+func maxLength(length *uint64) bound {
+	if length == nil {
+		return bound{}
+	}
+	value := float64(*length)
+	return bound{value: &value}
+}
+
+func (b bound) String() string {
+	switch {
+	case b.value == nil:
+		return "none"
+	case b.exclusive:
+		return fmt.Sprintf("%v (exclusive)", *b.value)
+	default:
+		return fmt.Sprint(*b.value)
+	}
+}
+
+// additionalProperties tells whether a schema accepts additional properties, and the schema they must match when it has one
+func additionalProperties(schema *openapi3.Schema) (bool, *openapi3.SchemaRef) {
+	if schema.AdditionalProperties.Has != nil && !*schema.AdditionalProperties.Has {
+		return false, nil
+	}
+	return true, schema.AdditionalProperties.Schema
+}
+
+// readOnly properties are not sent in requests, and writeOnly ones are not returned in responses
+func readOnly(schema *openapi3.SchemaRef) bool {
+	return schema.Value != nil && schema.Value.ReadOnly
+}
+
+func writeOnly(schema *openapi3.SchemaRef) bool {
+	return schema.Value != nil && schema.Value.WriteOnly
+}
+
+// enumDifference returns the values of the base enum missing from the revision one, and the other way around, as JSON
+func enumDifference(base, revision []any) ([]string, []string) {
+	baseValues, revisionValues := enumSet(base), enumSet(revision)
+	var removed, added []string
+	for _, value := range sortedKeys(baseValues) {
+		if len(revision) > 0 && !revisionValues[value] {
+			removed = append(removed, value)
+		}
+	}
+	for _, value := range sortedKeys(revisionValues) {
+		if !baseValues[value] {
+			added = append(added, value)
+		}
+	}
+	return removed, added
+}
+
+func enumSet(values []any) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded = []byte(fmt.Sprint(value))
+		}
+		set[string(encoded)] = true
+	}
+	return set
+}
+
+// enumValues describes the values no longer accepted, when the base enum had some
+func enumValues(removed []string, base []any) string {
+	if len(removed) == 0 && len(base) == 0 {
+		return "values outside of its new enum"
+	}
+	return strings.Join(removed, ", ")
+}
+
+var pathParameter = regexp.MustCompile(`\{[^}]*}`)
+
+// normalizePath ignores the names of the path parameters, so that renaming them does not remove an operation
+func normalizePath(path string) string {
+	return pathParameter.ReplaceAllString(path, "{}")
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,183 @@
+package spec
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"testing"
+)
+
+const diffBase = `openapi: 3.0.3
+info: {title: diff, version: "1"}
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name: {type: string}
+                role: {type: string, enum: [admin, user]}
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+  /users/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        "200":
+          description: ok
+    delete:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        "204": {description: deleted}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: integer}
+        email: {type: string}
+`
+
+const diffRevision = `openapi: 3.0.3
+info: {title: diff, version: "2"}
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name, team]
+              properties:
+                name: {type: string}
+                role: {type: string, enum: [admin]}
+                team: {type: string}
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+  /users/{userId}:
+    get:
+      parameters:
+        - {name: userId, in: path, required: true, schema: {type: integer}}
+        - {name: fields, in: query, schema: {type: string}}
+      responses:
+        "200":
+          description: ok
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: string}
+`
+
+func loadDiffVersion(t *testing.T, file string, data string) Version {
+	doc, err := openapi3.NewLoader().LoadFromDataWithPath([]byte(data), &url.URL{Path: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Version{Doc: doc, File: file}
+}
+
+func TestDiff(t *testing.T) {
+	changes := Diff(loadDiffVersion(t, "base.yaml", diffBase), loadDiffVersion(t, "revision.yaml", diffRevision))
+	expected := []struct {
+		id       string
+		breaking bool
+		location string
+	}{
+		{"request-property-became-required", true, "revision.yaml#/paths/~1users/post/requestBody/content/application~1json/schema/required"},
+		{"request-added-required-property", true, "revision.yaml#/paths/~1users/post/requestBody/content/application~1json/schema/properties/team"},
+		{"request-narrowed-enum", true, "revision.yaml#/paths/~1users/post/requestBody/content/application~1json/schema/properties/role"},
+		{"response-removed-property", true, "base.yaml#/components/schemas/User/properties/email"},
+		{"response-changed-type", true, "revision.yaml#/components/schemas/User/properties/id"},
+		{"removed-operation", true, "base.yaml#/paths/~1users~1{id}/delete"},
+		{"added-optional-parameter", false, "revision.yaml#/paths/~1users~1{userId}/get/parameters/1"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatal(len(changes), changes)
+	}
+	for i, elem := range expected {
+		if changes[i].Id != elem.id || changes[i].Breaking != elem.breaking || changes[i].Location.String() != elem.location {
+			t.Fatal(i, changes[i])
+		}
+	}
+}
+
+func diffSchemaSpec(direction string, schema string, named string) string {
+	operation := "requestBody: {content: {application/json: {schema: " + schema + "}}}\n      responses: {\"204\": {description: ok}}"
+	if direction == "response" {
+		operation = "responses: {\"200\": {description: ok, content: {application/json: {schema: " + schema + "}}}}"
+	}
+	return `openapi: 3.0.3
+info: {title: diff, version: "1"}
+paths:
+  /users:
+    post:
+      ` + operation + `
+components:
+  schemas:
+    Named: ` + named + `
+`
+}
+
+func TestDiffSchema(t *testing.T) {
+	const named = `{type: object, properties: {id: {type: integer}}}`
+	const ref = `{$ref: "#/components/schemas/Named"}`
+	scenario := []struct {
+		direction      string
+		base, revision string
+		revisionNamed  string
+		id             string
+		breaking       bool
+		location       string
+	}{
+		{"request", `{allOf: [` + ref + `]}`, `{allOf: [` + ref + `, {required: [id]}]}`, "", "request-added-allof-schema", true, "revision.yaml#/paths/~1users/post/requestBody/content/application~1json/schema/allOf/1"},
+		{"response", `{allOf: [` + ref + `]}`, `{allOf: [` + ref + `]}`, `{type: object, properties: {id: {type: string}}}`, "response-changed-type", true, "revision.yaml#/components/schemas/Named/properties/id"},
+		{"response", `{allOf: [` + ref + `, {required: [id]}]}`, `{allOf: [` + ref + `]}`, "", "response-removed-allof-schema", true, "base.yaml#/paths/~1users/post/responses/200/content/application~1json/schema/allOf/1"},
+		{"request", `{allOf: [{type: object}, ` + ref + `]}`, `{allOf: [` + ref + `, {type: object, nullable: true}]}`, "", "request-became-nullable", false, "revision.yaml#/paths/~1users/post/requestBody/content/application~1json/schema/allOf/1"},
+		{"response", `{oneOf: [{type: string}]}`, `{oneOf: [{type: string}, {type: integer}]}`, "", "response-added-oneof-schema", true, ""},
+		{"request", `{anyOf: [{type: string}, {type: integer}]}`, `{anyOf: [{type: string}]}`, "", "request-removed-anyof-schema", true, ""},
+		{"request", `{type: object}`, `{type: object, additionalProperties: false}`, "", "request-disallowed-additional-properties", true, ""},
+		{"response", `{type: object, additionalProperties: {type: integer}}`, `{type: object, additionalProperties: {type: string}}`, "", "response-changed-type", true, "revision.yaml#/paths/~1users/post/responses/200/content/application~1json/schema/additionalProperties"},
+		{"response", `{type: object}`, `{type: object, nullable: true}`, "", "response-became-nullable", true, ""},
+		{"request", `{type: string, maxLength: 10}`, `{type: string, maxLength: 5}`, "", "request-narrowed-maxlength", true, ""},
+		{"response", `{type: string, maxLength: 10}`, `{type: string, maxLength: 5}`, "", "response-narrowed-maxlength", false, ""},
+		{"request", `{type: integer}`, `{type: integer, minimum: 1}`, "", "request-narrowed-minimum", true, ""},
+		{"response", `{type: integer, minimum: 1}`, `{type: integer, minimum: 1, exclusiveMinimum: true}`, "", "response-narrowed-minimum", false, ""},
+		{"request", `{type: string}`, `{type: string, pattern: "^[a-z]+$"}`, "", "request-added-pattern", true, ""},
+		{"response", `{type: string, pattern: "^[a-z]+$"}`, `{type: string}`, "", "response-removed-pattern", true, ""},
+		{"response", `{type: string, format: date-time}`, `{type: string, format: date}`, "", "response-changed-format", true, ""},
+	}
+	for i, elem := range scenario {
+		revisionNamed := named
+		if elem.revisionNamed != "" {
+			revisionNamed = elem.revisionNamed
+		}
+		changes := Diff(
+			loadDiffVersion(t, "base.yaml", diffSchemaSpec(elem.direction, elem.base, named)),
+			loadDiffVersion(t, "revision.yaml", diffSchemaSpec(elem.direction, elem.revision, revisionNamed)),
+		)
+		if len(changes) != 1 {
+			t.Fatal(i, changes)
+		}
+		if changes[0].Id != elem.id || changes[0].Breaking != elem.breaking {
+			t.Fatal(i, changes[0])
+		}
+		if elem.location != "" && changes[0].Location.String() != elem.location {
+			t.Fatal(i, changes[0].Location)
+		}
+	}
+}
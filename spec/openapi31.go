@@ -0,0 +1,289 @@
+package spec
+
+import (
+	"encoding/json"
+	"github.com/oasdiff/yaml"
+	"strings"
+)
+
+// operationKeys are the keys of a path item holding operations
+var operationKeys = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// jsonSchemaOnlyKeywords are the JSON Schema 2020-12 keywords unknown to OpenAPI 3.0 schemas
+var jsonSchemaOnlyKeywords = []string{
+	"$schema", "$id", "$anchor", "$dynamicAnchor", "$dynamicRef", "$defs", "$comment", "$vocabulary",
+	"prefixItems", "contains", "minContains", "maxContains", "unevaluatedItems", "unevaluatedProperties",
+	"patternProperties", "propertyNames", "dependentRequired", "dependentSchemas", "if", "then", "else",
+	"contentEncoding", "contentMediaType", "contentSchema", "examples", "const",
+}
+
+// IsOpenAPI31 tells whether a document is an OpenAPI 3.1 spec
+func IsOpenAPI31(data []byte) bool {
+	var header struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return false
+	}
+	return strings.HasPrefix(header.OpenAPI, "3.1")
+}
+
+// Downgrade turns an OpenAPI 3.1 spec, or a document it references, into an OpenAPI 3.0 one that kin-openapi loads
+// Its schemas are only an approximation used for routing and parameters, bodies are validated against the original ones
+func Downgrade(data []byte) ([]byte, error) {
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	if root, ok := document.(map[string]any); ok {
+		if _, ok = root["openapi"]; ok {
+			downgradeDocument(root)
+		} else if _, ok = root["paths"]; ok {
+			downgradeDocument(root)
+		} else if _, ok = root["components"]; ok {
+			downgradeDocument(root)
+		} else if looksLikeSchema(root) {
+			document = downgradeSchema(root)
+		} else {
+			// A file of definitions, referenced as file.yaml#/Name
+			for key, value := range root {
+				if definition, ok := value.(map[string]any); ok && looksLikeSchema(definition) {
+					root[key] = downgradeSchema(definition)
+				}
+			}
+		}
+	}
+	return json.Marshal(document)
+}
+
+func downgradeDocument(root map[string]any) {
+	if _, ok := root["openapi"]; ok {
+		root["openapi"] = "3.0.3"
+		if _, ok = root["paths"]; !ok {
+			root["paths"] = map[string]any{}
+		}
+	}
+	delete(root, "webhooks")
+	delete(root, "jsonSchemaDialect")
+	if info, ok := root["info"].(map[string]any); ok {
+		delete(info, "summary")
+		if license, ok := info["license"].(map[string]any); ok {
+			delete(license, "identifier")
+		}
+	}
+
+	if paths, ok := root["paths"].(map[string]any); ok {
+		for key := range paths {
+			paths[key] = downgradePathItem(paths[key])
+		}
+	}
+
+	components, ok := root["components"].(map[string]any)
+	if !ok {
+		return
+	}
+	delete(components, "pathItems")
+	downgradeEach(components["schemas"], downgradeSchema)
+	downgradeEach(components["parameters"], downgradeParameter)
+	downgradeEach(components["headers"], downgradeParameter)
+	downgradeEach(components["requestBodies"], downgradeContainer)
+	downgradeEach(components["responses"], downgradeResponse)
+	downgradeEach(components["callbacks"], downgradeCallback)
+}
+
+// downgradeEach downgrades every value of a map in place
+func downgradeEach(value any, downgrade func(any) any) {
+	if values, ok := value.(map[string]any); ok {
+		for key := range values {
+			values[key] = downgrade(values[key])
+		}
+	}
+}
+
+// downgradeList downgrades every value of a list in place
+func downgradeList(value any, downgrade func(any) any) {
+	if values, ok := value.([]any); ok {
+		for i := range values {
+			values[i] = downgrade(values[i])
+		}
+	}
+}
+
+// downgradeRef drops the siblings of a reference outside of a schema, which OpenAPI 3.0 does not allow
+func downgradeRef(value any) (any, bool) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return value, true
+	}
+	if ref, ok := object["$ref"]; ok {
+		return map[string]any{"$ref": ref}, true
+	}
+	return object, false
+}
+
+func downgradePathItem(value any) any {
+	value, isRef := downgradeRef(value)
+	if isRef {
+		return value
+	}
+	item := value.(map[string]any)
+	downgradeList(item["parameters"], downgradeParameter)
+	for _, key := range operationKeys {
+		operation, ok := item[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		downgradeList(operation["parameters"], downgradeParameter)
+		if body, ok := operation["requestBody"]; ok {
+			operation["requestBody"] = downgradeContainer(body)
+		}
+		downgradeEach(operation["responses"], downgradeResponse)
+		downgradeEach(operation["callbacks"], downgradeCallback)
+	}
+	return item
+}
+
+func downgradeCallback(value any) any {
+	value, isRef := downgradeRef(value)
+	if !isRef {
+		downgradeEach(value, downgradePathItem)
+	}
+	return value
+}
+
+func downgradeResponse(value any) any {
+	value, isRef := downgradeRef(value)
+	if !isRef {
+		downgradeEach(value.(map[string]any)["headers"], downgradeParameter)
+		value = downgradeContainer(value)
+	}
+	return value
+}
+
+// downgradeParameter downgrades a parameter or a header
+func downgradeParameter(value any) any {
+	value, isRef := downgradeRef(value)
+	if isRef {
+		return value
+	}
+	parameter := value.(map[string]any)
+	if schema, ok := parameter["schema"]; ok {
+		parameter["schema"] = downgradeSchema(schema)
+	}
+	return downgradeContainer(parameter)
+}
+
+// downgradeContainer downgrades an object holding content, such as a request body
+func downgradeContainer(value any) any {
+	value, isRef := downgradeRef(value)
+	if isRef {
+		return value
+	}
+	container := value.(map[string]any)
+	if content, ok := container["content"].(map[string]any); ok {
+		for _, mediaType := range content {
+			media, ok := mediaType.(map[string]any)
+			if !ok {
+				continue
+			}
+			if schema, ok := media["schema"]; ok {
+				media["schema"] = downgradeSchema(schema)
+			}
+			if encodings, ok := media["encoding"].(map[string]any); ok {
+				for _, encoding := range encodings {
+					if encoding, ok := encoding.(map[string]any); ok {
+						downgradeEach(encoding["headers"], downgradeParameter)
+					}
+				}
+			}
+		}
+	}
+	return container
+}
+
+// looksLikeSchema tells whether an object of an unknown document is a schema
+func looksLikeSchema(object map[string]any) bool {
+	for _, key := range []string{"type", "properties", "items", "allOf", "anyOf", "oneOf", "not", "$ref", "enum", "const"} {
+		if _, ok := object[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func downgradeSchema(value any) any {
+	switch schema := value.(type) {
+	case bool:
+		if schema {
+			return map[string]any{}
+		}
+		return map[string]any{"not": map[string]any{}}
+	case map[string]any:
+		if ref, ok := schema["$ref"].(string); ok {
+			// References to anchors and to $defs only resolve in JSON Schema
+			_, fragment, _ := strings.Cut(ref, "#")
+			if strings.Contains(fragment, "/$defs/") || (fragment != "" && !strings.HasPrefix(fragment, "/")) {
+				return map[string]any{}
+			}
+			return map[string]any{"$ref": ref}
+		}
+
+		if types, ok := schema["type"].([]any); ok {
+			var kept []any
+			for _, typeName := range types {
+				if typeName == "null" {
+					schema["nullable"] = true
+				} else {
+					kept = append(kept, typeName)
+				}
+			}
+			if len(kept) == 1 {
+				schema["type"] = kept[0]
+			} else {
+				delete(schema, "type")
+			}
+		} else if schema["type"] == "null" {
+			delete(schema, "type")
+			schema["nullable"] = true
+		}
+		if constant, ok := schema["const"]; ok {
+			if _, ok = schema["enum"]; !ok {
+				schema["enum"] = []any{constant}
+			}
+		}
+		if examples, ok := schema["examples"].([]any); ok && len(examples) > 0 {
+			if _, ok = schema["example"]; !ok {
+				schema["example"] = examples[0]
+			}
+		}
+		for _, bound := range []string{"Minimum", "Maximum"} {
+			if limit, ok := schema["exclusive"+bound].(float64); ok {
+				schema[strings.ToLower(bound)] = limit
+				schema["exclusive"+bound] = true
+			}
+		}
+		for _, keyword := range jsonSchemaOnlyKeywords {
+			delete(schema, keyword)
+		}
+
+		downgradeEach(schema["properties"], downgradeSchema)
+		if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+			schema["additionalProperties"] = downgradeSchema(additional)
+		}
+		for _, keyword := range []string{"items", "not"} {
+			if subschema, ok := schema[keyword]; ok {
+				schema[keyword] = downgradeSchema(subschema)
+			}
+		}
+		for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+			downgradeList(schema[keyword], downgradeSchema)
+		}
+		if _, ok := schema["items"]; !ok && schema["type"] == "array" {
+			schema["items"] = map[string]any{}
+		}
+		return schema
+	default:
+		return value
+	}
+}
@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"testing"
+)
+
+func TestIsOpenAPI31(t *testing.T) {
+	if !IsOpenAPI31([]byte("openapi: 3.1.0\n")) || !IsOpenAPI31([]byte(`{"openapi": "3.1.1"}`)) {
+		t.Fatal("3.1 specs should be detected")
+	}
+	if IsOpenAPI31([]byte("openapi: 3.0.3\n")) || IsOpenAPI31([]byte("swagger: \"2.0\"\n")) {
+		t.Fatal("other versions should not be detected")
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	data, err := Downgrade([]byte(`openapi: 3.1.0
+info: {title: test, version: "1", summary: users}
+webhooks: {}
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: [integer, "null"]}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        name: {type: [string, "null"]}
+        kind: {const: person}
+        position: {type: array, prefixItems: [{type: number}], items: false}
+        age: {type: integer, exclusiveMinimum: 0}
+        tag: {$ref: "#/components/schemas/User/$defs/Tag"}
+      $defs:
+        Tag: {type: string}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatal(err, string(data))
+	}
+
+	user := doc.Components.Schemas["User"].Value
+	name := user.Properties["name"].Value
+	if !name.Type.Is("string") || !name.Nullable {
+		t.Fatal("type lists with null should be nullable", string(data))
+	}
+	if kind := user.Properties["kind"].Value; len(kind.Enum) != 1 || kind.Enum[0] != "person" {
+		t.Fatal("const should be an enum", string(data))
+	}
+	if age := user.Properties["age"].Value; !age.ExclusiveMin || age.Min == nil || *age.Min != 0 {
+		t.Fatal("numeric exclusiveMinimum should be a boolean", string(data))
+	}
+	if id := doc.Paths.Find("/users/{id}").Get.Parameters[0].Value.Schema.Value; !id.Type.Is("integer") {
+		t.Fatal("parameter schemas should be downgraded", string(data))
+	}
+}
@@ -0,0 +1,221 @@
+package spec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"github.com/speakeasy-api/jsonpath/pkg/overlay"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"path"
+	"static-openapivalidator/logger"
+	"strconv"
+	"strings"
+)
+
+// ApplyOverlays applies OpenAPI Overlay files to the spec document at location, in order, and returns the effective spec as YAML
+// Overlays only reach the document itself: an action whose target lies in a document referenced with $ref fails,
+// rather than silently changing nothing. The referenced documents are read with the loader to tell so
+// The result must be loaded with the location of the document for its relative references to be resolved
+func ApplyOverlays(data []byte, location *url.URL, loader *openapi3.Loader, overlayPaths []string) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	for _, path := range overlayPaths {
+		parsed, err := overlay.Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = parsed.Validate(); err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+		logger.Log("Overlay %s: applying %d actions", path, len(parsed.Actions))
+		// Actions are applied one at a time, so that each target is checked against the outcome of the previous ones
+		for _, action := range parsed.Actions {
+			if err = checkTarget(&root, location, loader, action.Target); err != nil {
+				return nil, errors.New(path + ": " + err.Error())
+			}
+			single := *parsed
+			single.Actions = []overlay.Action{action}
+			if err = single.ApplyTo(&root); err != nil {
+				return nil, errors.New(path + ": " + err.Error())
+			}
+		}
+	}
+	return encode(&root)
+}
+
+// checkTarget fails when the target of an action selects nothing in the document, but would in a document it references
+func checkTarget(root *yaml.Node, location *url.URL, loader *openapi3.Loader, target string) error {
+	if target == "" {
+		return nil
+	}
+	query, err := jsonpath.NewPath(target, config.WithPropertyNameExtension())
+	if err != nil {
+		return err
+	}
+	if len(query.Query(root)) > 0 {
+		return nil
+	}
+
+	inliner := refInliner{loader: loader, documents: make(map[string]*yaml.Node), sources: make(map[*yaml.Node]string)}
+	for _, node := range query.Query(inliner.inline(root, location, nil)) {
+		if source, ok := inliner.sources[node]; ok {
+			return fmt.Errorf("target %s lies in %s, referenced with $ref, overlays only apply to the document of the spec", target, source)
+		}
+	}
+	logger.Log("Overlay target %s: matches nothing", target)
+	return nil
+}
+
+// refInliner copies a document with its external $refs replaced by their targets, remembering which document every copied node comes from
+type refInliner struct {
+	loader    *openapi3.Loader
+	documents map[string]*yaml.Node
+	sources   map[*yaml.Node]string
+}
+
+// inline copies node, read from base, refs are the external $refs being inlined, so that cycles are kept as $refs
+func (r *refInliner) inline(node *yaml.Node, base *url.URL, refs []string) *yaml.Node {
+	if ref := refValue(node); ref != "" && !strings.HasPrefix(ref, "#") {
+		if target, location, file, ok := r.resolve(ref, base); ok {
+			for _, visiting := range refs {
+				if visiting == location.String() {
+					return node
+				}
+			}
+			copied := r.inline(target, location, append(refs, location.String()))
+			r.mark(copied, file)
+			return copied
+		}
+	}
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = r.inline(child, base, refs)
+	}
+	return &copied
+}
+
+// resolve returns the node a $ref points to, along with its location and the one of its document
+func (r *refInliner) resolve(ref string, base *url.URL) (*yaml.Node, *url.URL, string, bool) {
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return nil, nil, "", false
+	}
+	location := parsed
+	if parsed.Scheme == "" && parsed.Host == "" {
+		// As the loader does, so that relative locations of local files stay relative
+		location = &url.URL{Scheme: base.Scheme, Host: base.Host, Path: parsed.Path, Fragment: parsed.Fragment}
+		if !path.IsAbs(parsed.Path) {
+			location.Path = path.Join(path.Dir(base.Path), parsed.Path)
+		}
+	}
+	file := *location
+	file.Fragment, file.RawFragment = "", ""
+	document, ok := r.documents[file.String()]
+	if !ok {
+		read := r.loader.ReadFromURIFunc
+		if read == nil {
+			read = openapi3.DefaultReadFromURI
+		}
+		if data, err := read(r.loader, &file); err == nil {
+			document = &yaml.Node{}
+			if yaml.Unmarshal(data, document) != nil {
+				document = nil
+			}
+		}
+		r.documents[file.String()] = document
+	}
+	if document == nil {
+		return nil, nil, "", false
+	}
+	target := document
+	if target.Kind == yaml.DocumentNode && len(target.Content) > 0 {
+		target = target.Content[0]
+	}
+	for _, token := range splitPointer(location.Fragment) {
+		target = yamlChild(target, token)
+		if target == nil {
+			return nil, nil, "", false
+		}
+	}
+	return target, location, file.String(), true
+}
+
+// mark records the document every node of a copied tree comes from, the innermost documents are marked first and kept
+func (r *refInliner) mark(node *yaml.Node, source string) {
+	if _, ok := r.sources[node]; !ok {
+		r.sources[node] = source
+	}
+	for _, child := range node.Content {
+		r.mark(child, source)
+	}
+}
+
+// refValue is the $ref of a mapping node, if any
+func refValue(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "$ref" && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// yamlChild is the value of a key of a mapping node, or an item of a sequence node
+func yamlChild(node *yaml.Node, token string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == token {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if strconv.Itoa(i) == token {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// Marshal returns a spec document as YAML
+func Marshal(data []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return encode(&root)
+}
+
+// encode writes a YAML tree in block style
+func encode(root *yaml.Node) ([]byte, error) {
+	resetStyle(root)
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	err := encoder.Close()
+	return buffer.Bytes(), err
+}
+
+// resetStyle drops the JSON flow style and quotes, keeping the ones required by the values
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
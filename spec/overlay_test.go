@@ -0,0 +1,104 @@
+package spec
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyOverlays(t *testing.T) {
+	data := []byte(`{"openapi": "3.0.3",
+"info": {"title": "test", "version": "1"},
+"paths": {
+  "/users": {"get": {"responses": {"200": {"description": "ok"}}}},
+  "/internal": {"get": {"responses": {"200": {"description": "ok"}}}}
+}}`)
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	_ = os.WriteFile(first, []byte(`overlay: 1.0.0
+info: {title: first, version: "1"}
+actions:
+  - target: $.paths['/internal']
+    remove: true
+  - target: $.info
+    update: {title: updated}
+`), 0644)
+	_ = os.WriteFile(second, []byte(`overlay: 1.0.0
+info: {title: second, version: "1"}
+actions:
+  - target: $.info
+    update: {title: second}
+`), 0644)
+
+	effective, err := ApplyOverlays(data, &url.URL{Path: filepath.Join(dir, "openapi.json")}, openapi3.NewLoader(), []string{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(effective)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Paths.Find("/internal") != nil || doc.Paths.Find("/users") == nil {
+		t.Fatal(string(effective))
+	}
+	if doc.Info.Title != "second" {
+		t.Fatal("overlays should be applied in order", doc.Info.Title)
+	}
+	if doc.Paths.Find("/users").Get.Responses.Status(200) == nil {
+		t.Fatal("status codes should stay strings", string(effective))
+	}
+
+	_ = os.WriteFile(first, []byte("overlay: 2.0.0\nactions: []\n"), 0644)
+	if _, err = ApplyOverlays(data, &url.URL{Path: filepath.Join(dir, "openapi.json")}, openapi3.NewLoader(), []string{first}); err == nil {
+		t.Fatal("invalid overlay should fail")
+	}
+}
+
+func TestApplyOverlaysReferencedTargets(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(dir, "schemas"), 0755)
+	_ = os.WriteFile(filepath.Join(dir, "schemas", "user.yaml"), []byte("type: object\nrequired: [name]\nproperties:\n  name: {type: string}\n"), 0644)
+	data := []byte(`openapi: 3.0.3
+info: {title: test, version: "1"}
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+components:
+  schemas:
+    User: {$ref: "./schemas/user.yaml"}
+`)
+	location := &url.URL{Path: filepath.Join(dir, "openapi.yaml")}
+
+	scenario := []struct {
+		action string
+		fails  bool
+	}{
+		{"target: $.paths['/users'].get.responses['200']\n    update: {description: found}", false},
+		// Matching nothing anywhere is allowed by the Overlay specification
+		{"target: $.paths['/internal']\n    remove: true", false},
+		// The schema lies in schemas/user.yaml, the overlay cannot reach it
+		{"target: $.components.schemas.User.required\n    remove: true", true},
+	}
+	for i, elem := range scenario {
+		overlayPath := filepath.Join(dir, "overlay.yaml")
+		_ = os.WriteFile(overlayPath, []byte("overlay: 1.0.0\ninfo: {title: test, version: \"1\"}\nactions:\n  - "+elem.action+"\n"), 0644)
+		_, err := ApplyOverlays(data, location, openapi3.NewLoader(), []string{overlayPath})
+		if (err != nil) != elem.fails {
+			t.Fatal(i, err)
+		}
+		if elem.fails && !strings.Contains(err.Error(), filepath.Join(dir, "schemas", "user.yaml")) {
+			t.Fatal(i, "the error should name the referenced file", err)
+		}
+	}
+}
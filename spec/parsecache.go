@@ -0,0 +1,252 @@
+package spec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"static-openapivalidator/logger"
+	"strings"
+)
+
+// parseCacheVersion changes whenever the entries of the parse cache are no longer compatible
+const parseCacheVersion = 1
+
+// Kinds of specs, as they are loaded
+const (
+	KindOpenAPI30 = ""
+	KindOpenAPI31 = "openapi31"
+	KindSwagger2  = "swagger2"
+)
+
+// ParseCache keeps the specs once loaded and validated on disk, bundled so that loading them resolves no external $ref
+// An entry is only used when the content of every file it was loaded from is unchanged
+// Routers are not cached, they hold compiled patterns and pointers into the loaded spec that cannot be written to disk
+type ParseCache struct {
+	Dir string
+}
+
+// Cached is a spec loaded from the parse cache
+type Cached struct {
+	Doc  *openapi3.T
+	Kind string
+	// Locations maps the components external documents were bundled into back to these documents, nil when there are none
+	// The errors of the spec name these components
+	Locations *strings.Replacer
+	// Read reads the documents referenced by the spec as they were cached, only OpenAPI 3.1 specs keep them
+	Read openapi3.ReadFromURIFunc
+}
+
+// Recording tracks the documents read while loading a spec, so that it can be cached once validated
+type Recording struct {
+	files     map[string]parseFile
+	documents map[string]json.RawMessage
+}
+
+type parseFile struct {
+	Hash string `json:"hash"`
+	// Size and ModTime are set for local files, which are not read again as long as they are unchanged
+	Size    int64 `json:"size,omitempty"`
+	ModTime int64 `json:"modTime,omitempty"`
+}
+
+type parseEntry struct {
+	Version   int                        `json:"version"`
+	Kind      string                     `json:"kind"`
+	Files     map[string]parseFile       `json:"files"`
+	Spec      json.RawMessage            `json:"spec"`
+	Sources   SourceMap                  `json:"sources"`
+	Documents map[string]json.RawMessage `json:"documents,omitempty"`
+}
+
+// NewRecording starts the recording of the loading of the spec at location, whose content is data
+func NewRecording(location *url.URL, data []byte) *Recording {
+	return &Recording{
+		files:     map[string]parseFile{location.String(): {Hash: hashContent(data)}},
+		documents: make(map[string]json.RawMessage),
+	}
+}
+
+// Files wraps the reader of the documents, to hash and keep them as read
+func (r *Recording) Files(read openapi3.ReadFromURIFunc) openapi3.ReadFromURIFunc {
+	return func(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+		data, err := read(loader, location)
+		if _, ok := r.files[location.String()]; err == nil && !ok {
+			file := parseFile{Hash: hashContent(data)}
+			if isLocal(location) {
+				if info, statErr := os.Stat(filepath.FromSlash(location.Path)); statErr == nil {
+					file.Size, file.ModTime = info.Size(), info.ModTime().UnixNano()
+				}
+			}
+			r.files[location.String()] = file
+			if document, jsonErr := yaml.YAMLToJSON(data); jsonErr == nil {
+				r.documents[location.String()] = document
+			}
+		}
+		return data, err
+	}
+}
+
+// Load returns the cached spec loaded from location, whose content is data
+// Local files are checked by size and modification time, remote documents are read again with read
+func (c *ParseCache) Load(key string, location *url.URL, data []byte, loader *openapi3.Loader, read openapi3.ReadFromURIFunc) (*Cached, bool) {
+	path := c.entryPath(key)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log("Parse cache: could not read %s: %v", path, err)
+		}
+		return nil, false
+	}
+	var entry parseEntry
+	if err = json.Unmarshal(content, &entry); err != nil || entry.Version != parseCacheVersion {
+		logger.Log("Parse cache: ignoring %s, outdated", path)
+		return nil, false
+	}
+
+	for fileLocation, file := range entry.Files {
+		if fileLocation == location.String() {
+			if hashContent(data) != file.Hash {
+				return nil, false
+			}
+			continue
+		}
+		if !file.unchanged(fileLocation, loader, read) {
+			logger.Log("Parse cache: %s changed", fileLocation)
+			return nil, false
+		}
+	}
+
+	// The spec is bundled, the documents are only read by the JSON Schemas of OpenAPI 3.1 bodies
+	cachedRead := func(loader *openapi3.Loader, documentLocation *url.URL) ([]byte, error) {
+		if document, ok := entry.Documents[documentLocation.String()]; ok {
+			return document, nil
+		}
+		return nil, fmt.Errorf("%s is not in the parse cache", documentLocation)
+	}
+	loader.ReadFromURIFunc = cachedRead
+	doc, err := loader.LoadFromDataWithPath(entry.Spec, location)
+	if err != nil {
+		logger.Log("Parse cache: could not load %s: %v", path, err)
+		return nil, false
+	}
+	return &Cached{
+		Doc:       doc,
+		Kind:      entry.Kind,
+		Locations: bundledLocations(entry.Sources, location.String(), entry.Kind),
+		Read:      cachedRead,
+	}, true
+}
+
+// Store bundles a spec once validated and caches it, it returns how to map the locations of the bundled spec back like Load does
+// Failing to write the entry only costs a full load on the next run, so it is logged and not returned
+func (c *ParseCache) Store(ctx context.Context, key string, location *url.URL, kind string, doc *openapi3.T, recording *Recording) *strings.Replacer {
+	path := c.entryPath(key)
+	sources := Bundle(ctx, doc, location.String())
+	spec, err := json.Marshal(doc)
+	if err == nil {
+		entry := parseEntry{
+			Version: parseCacheVersion,
+			Kind:    kind,
+			Files:   recording.files,
+			Spec:    spec,
+			Sources: sources,
+		}
+		if kind == KindOpenAPI31 {
+			entry.Documents = recording.documents
+		}
+		var content []byte
+		content, err = json.Marshal(entry)
+		if err == nil {
+			err = os.MkdirAll(c.Dir, 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, content, 0o644)
+		}
+	}
+	if err != nil {
+		logger.Log("Parse cache: could not write %s: %v", path, err)
+	}
+	return bundledLocations(sources, location.String(), kind)
+}
+
+// unchanged tells whether the file cached from location still has the same content
+func (f parseFile) unchanged(location string, loader *openapi3.Loader, read openapi3.ReadFromURIFunc) bool {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	var data []byte
+	if isLocal(parsed) {
+		path := filepath.FromSlash(parsed.Path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		if info.Size() == f.Size && info.ModTime().UnixNano() == f.ModTime {
+			return true
+		}
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = read(loader, parsed)
+	}
+	return err == nil && hashContent(data) == f.Hash
+}
+
+// bundledLocations maps the components of the spec bundled from root back to the documents they were bundled from
+// Errors name the components, so both the components and their locations in root are mapped
+func bundledLocations(sources SourceMap, root string, kind string) *strings.Replacer {
+	var bundled []string
+	targets := make(map[string]string)
+	for component, source := range sources {
+		if source.File == root {
+			continue
+		}
+		target := source.String()
+		if source.Pointer == "" {
+			target = source.File
+		}
+		if kind == KindSwagger2 {
+			target = Swagger2Locations.Replace(target)
+		}
+		bundled = append(bundled, root+component, component)
+		targets[root+component], targets[component] = target, target
+	}
+	if len(bundled) == 0 {
+		return nil
+	}
+	// The longest come first, so that #/components/schemas/users is not taken for #/components/schemas/user
+	sort.Slice(bundled, func(i, j int) bool {
+		return len(bundled[i]) > len(bundled[j])
+	})
+	var pairs []string
+	for _, component := range bundled {
+		pairs = append(pairs, component, targets[component])
+	}
+	if kind == KindSwagger2 {
+		pairs = append(pairs, swagger2Locations...)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func (c *ParseCache) entryPath(key string) string {
+	return filepath.Join(c.Dir, hashContent([]byte(key))+".json")
+}
+
+func isLocal(location *url.URL) bool {
+	return location.Scheme == "" || location.Scheme == "file"
+}
+
+func hashContent(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,92 @@
+package spec
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadWithParseCache(t *testing.T, cache *ParseCache, specPath string) (*Cached, bool, int) {
+	location := &url.URL{Path: filepath.ToSlash(specPath)}
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads := 0
+	read := func(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+		reads++
+		return openapi3.ReadFromFile(loader, location)
+	}
+	loader := &openapi3.Loader{IsExternalRefsAllowed: true}
+	if cached, ok := cache.Load(specPath, location, data, loader, read); ok {
+		return cached, true, reads
+	}
+
+	recording := NewRecording(location, data)
+	loader.ReadFromURIFunc = recording.Files(read)
+	doc, err := loader.LoadFromDataWithPath(data, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	locations := cache.Store(context.Background(), specPath, location, KindOpenAPI30, doc, recording)
+	return &Cached{Doc: doc, Kind: KindOpenAPI30, Locations: locations}, false, reads
+}
+
+func TestParseCache(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "openapi.yaml")
+	schemaPath := filepath.Join(dir, "schemas", "user.yaml")
+	if err := os.MkdirAll(filepath.Dir(schemaPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(specPath, []byte(remoteSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemaPath, []byte(remoteSchema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := &ParseCache{Dir: filepath.Join(dir, "cache")}
+
+	scenario := []struct {
+		schema string
+		cached bool
+		idType string
+	}{
+		{"", false, "integer"},
+		{"", true, "integer"},
+		{"type: object\nproperties:\n  id:\n    type: string\n", false, "string"},
+		{"", true, "string"},
+	}
+	for i, elem := range scenario {
+		if elem.schema != "" {
+			if err := os.WriteFile(schemaPath, []byte(elem.schema), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		loaded, cached, reads := loadWithParseCache(t, cache, specPath)
+		if cached != elem.cached {
+			t.Fatal(i, "cached", cached)
+		}
+		// A hit neither reads the referenced schema nor resolves its $ref, the schema is bundled
+		if cached && reads != 0 {
+			t.Fatal(i, "reads", reads)
+		}
+		schemaRef := loaded.Doc.Paths.Value("/users").Get.Responses.Status(200).Value.Content.Get("application/json").Schema
+		if schemaRef.Ref != "#/components/schemas/schemas_user" || !schemaRef.Value.Properties["id"].Value.Type.Is(elem.idType) {
+			t.Fatal(i, schemaRef.Ref, schemaRef.Value.Properties["id"].Value.Type)
+		}
+		// Both the location of the bundled schema and its name are mapped back to the schema file
+		if source := loaded.Locations.Replace(schemaRef.RefPath().String()); source != filepath.ToSlash(schemaPath) {
+			t.Fatal(i, source)
+		}
+		if name := loaded.Locations.Replace(schemaRef.Ref); name != filepath.ToSlash(schemaPath) {
+			t.Fatal(i, name)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package spec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"static-openapivalidator/logger"
+	"strings"
+	"time"
+)
+
+// Remote fetches specs over HTTP, keeping a copy of every document on disk
+// The copies are revalidated with their ETag, and used as is when the server cannot be reached
+type Remote struct {
+	// Headers are only sent to the host of the spec, not to the hosts of its external references
+	Headers  http.Header
+	Host     string
+	CacheDir string
+	Client   *http.Client
+}
+
+// IsRemote tells whether a spec location is an HTTP URL rather than a file
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// NewRemote prepares the fetching of the spec at location, headers are given as "Name: value"
+// An empty cache directory defaults to the cache directory of the user
+func NewRemote(location string, headers []string, cacheDir string) (*Remote, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := &Remote{
+		Headers:  http.Header{},
+		Host:     parsed.Host,
+		CacheDir: cacheDir,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, header := range headers {
+		key, value, found := strings.Cut(header, ":")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid spec header %q, expected \"Name: value\"", header)
+		}
+		remote.Headers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	if remote.CacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			logger.Log("Spec cache: disabled, %v", err)
+		} else {
+			remote.CacheDir = filepath.Join(userCacheDir, "static-openapivalidator", "specs")
+		}
+	}
+	return remote, nil
+}
+
+// ReadFromURI is an openapi3.ReadFromURIFunc for HTTP locations
+func (r *Remote) ReadFromURI(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+	if location.Scheme != "http" && location.Scheme != "https" {
+		return nil, openapi3.ErrURINotSupported
+	}
+
+	cached, etag := r.readCache(location)
+
+	ctx := loader.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if location.Host == r.Host {
+		for key, values := range r.Headers {
+			req.Header[key] = values
+		}
+	}
+	if cached != nil && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return r.fallback(location, cached, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		logger.Log("Spec cache: %s not modified", location)
+		return cached, nil
+	case resp.StatusCode >= 500:
+		return r.fallback(location, cached, fmt.Errorf("request returned status code %d", resp.StatusCode))
+	case resp.StatusCode > 399:
+		return nil, fmt.Errorf("error loading %q: request returned status code %d", location.String(), resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return r.fallback(location, cached, err)
+	}
+	r.writeCache(location, body, resp.Header.Get("ETag"))
+	return body, nil
+}
+
+// fallback uses the cached copy of a document that could not be fetched
+func (r *Remote) fallback(location *url.URL, cached []byte, err error) ([]byte, error) {
+	if cached == nil {
+		return nil, fmt.Errorf("error loading %q: %v", location.String(), err)
+	}
+	logger.Log("Spec cache: using the cached copy of %s: %v", location, err)
+	return cached, nil
+}
+
+func (r *Remote) cachePath(location *url.URL) string {
+	hash := sha256.Sum256([]byte(location.String()))
+	return filepath.Join(r.CacheDir, hex.EncodeToString(hash[:]))
+}
+
+// readCache returns the cached copy of a document along with its ETag, nil when there is none
+func (r *Remote) readCache(location *url.URL) ([]byte, string) {
+	if r.CacheDir == "" {
+		return nil, ""
+	}
+	path := r.cachePath(location)
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Log("Spec cache: could not read %s: %v", path, err)
+		}
+		return nil, ""
+	}
+	etag, _ := os.ReadFile(path + ".etag")
+	return body, string(etag)
+}
+
+// writeCache stores a document for the next runs, failing to do so only means it cannot be revalidated or used offline
+func (r *Remote) writeCache(location *url.URL, body []byte, etag string) {
+	if r.CacheDir == "" {
+		return
+	}
+	path := r.cachePath(location)
+	err := os.MkdirAll(r.CacheDir, 0o755)
+	if err == nil {
+		err = os.WriteFile(path, body, 0o644)
+	}
+	if err == nil {
+		err = os.WriteFile(path+".etag", []byte(etag), 0o644)
+	}
+	if err != nil {
+		logger.Log("Spec cache: could not write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const remoteSpec = `openapi: 3.0.3
+info:
+  title: remote
+  version: "1"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: "schemas/user.yaml"
+`
+
+const remoteSchema = `type: object
+properties:
+  id:
+    type: integer
+`
+
+func TestRemote(t *testing.T) {
+	var authorizations, revalidations int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token" {
+			authorizations++
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		switch r.URL.Path {
+		case "/api/openapi.yaml":
+			_, _ = w.Write([]byte(remoteSpec))
+		case "/api/schemas/user.yaml":
+			_, _ = w.Write([]byte(remoteSchema))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	location := server.URL + "/api/openapi.yaml"
+	remote, err := NewRemote(location, []string{"Authorization: Bearer token"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := func() *openapi3.T {
+		loader := &openapi3.Loader{Context: context.Background(), IsExternalRefsAllowed: true}
+		loader.ReadFromURIFunc = openapi3.ReadFromURIs(remote.ReadFromURI, openapi3.ReadFromFile)
+		parsed, _ := url.Parse(location)
+		doc, err := loader.LoadFromURI(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	doc := load()
+	schema := doc.Paths.Find("/users").Get.Responses.Status(200).Value.Content.Get("application/json").Schema
+	if schema.Value == nil || schema.Value.Properties["id"] == nil {
+		t.Fatal("relative reference was not resolved")
+	}
+	if authorizations != 2 {
+		t.Fatal(authorizations)
+	}
+
+	load()
+	if revalidations != 2 {
+		t.Fatal(revalidations)
+	}
+
+	// Offline, the cached copies are used
+	server.Close()
+	load()
+
+	if _, err = NewRemote(location, []string{"Authorization"}, ""); err == nil {
+		t.Fatal("header without a value should fail")
+	}
+}
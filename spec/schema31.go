@@ -0,0 +1,371 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/oasdiff/yaml"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"static-openapivalidator/validator"
+	"strconv"
+	"strings"
+)
+
+// Schemas validates JSON bodies against the schemas of an OpenAPI 3.1 spec, as JSON Schema 2020-12
+type Schemas struct {
+	location  string
+	read      func(location *url.URL) ([]byte, error)
+	documents map[string]any
+	compiler  *jsonschema.Compiler
+	compiled  map[string]*jsonschema.Schema
+}
+
+// schemaNode is a value of a document of the spec, along with its location
+type schemaNode struct {
+	document string
+	pointer  []string
+	value    any
+}
+
+var errorPrinter = message.NewPrinter(language.English)
+
+// NewSchemas prepares the validation of bodies against the spec at location, whose content is data
+// read is used to read the documents referenced by the spec
+func NewSchemas(location *url.URL, data []byte, read func(location *url.URL) ([]byte, error)) (*Schemas, error) {
+	absolute := *location
+	if absolute.Scheme == "" {
+		path, err := filepath.Abs(filepath.FromSlash(location.Path))
+		if err != nil {
+			return nil, err
+		}
+		absolute = url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	}
+
+	schemas := &Schemas{
+		location:  absolute.String(),
+		read:      read,
+		documents: make(map[string]any),
+		compiler:  jsonschema.NewCompiler(),
+		compiled:  make(map[string]*jsonschema.Schema),
+	}
+	root, err := decodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	schemas.documents[schemas.location] = root
+
+	schemas.compiler.DefaultDraft(jsonschema.Draft2020)
+	schemas.compiler.AssertFormat()
+	schemas.compiler.UseLoader(schemas)
+	if err = schemas.compiler.AddResource(schemas.location, root); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// Load is the jsonschema.URLLoader of the documents referenced by the spec
+func (s *Schemas) Load(location string) (any, error) {
+	return s.document(location)
+}
+
+// Handles tells whether the bodies of a content type are JSON, other bodies are left to kin-openapi
+func (s *Schemas) Handles(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (s *Schemas) ValidateRequestBody(route *routers.Route, contentType string, body []byte) error {
+	operation, err := s.operation(route)
+	if err != nil {
+		return err
+	}
+	requestBody, found, err := s.child(operation, "requestBody")
+	if err != nil || !found {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if object, ok := requestBody.value.(map[string]any); ok && object["required"] == true {
+			return errors.New("value is required but missing")
+		}
+		return nil
+	}
+	return s.validateContent(requestBody, contentType, body, "header Content-Type has unexpected value")
+}
+
+func (s *Schemas) ValidateResponseBody(route *routers.Route, status int, contentType string, body []byte) error {
+	operation, err := s.operation(route)
+	if err != nil {
+		return err
+	}
+	responses, found, err := s.child(operation, "responses")
+	if err != nil || !found {
+		return err
+	}
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		response, found, err := s.child(responses, key)
+		if err != nil {
+			return err
+		}
+		if found {
+			return s.validateContent(response, contentType, body, "response header Content-Type has unexpected value")
+		}
+	}
+	// kin-openapi reports the statuses missing from the spec
+	return nil
+}
+
+// operation returns the operation of a route in the original spec
+func (s *Schemas) operation(route *routers.Route) (schemaNode, error) {
+	paths, found, err := s.child(schemaNode{document: s.location, value: s.documents[s.location]}, "paths")
+	if err == nil && found {
+		var pathItem schemaNode
+		if pathItem, found, err = s.child(paths, route.Path); err == nil && found {
+			var operation schemaNode
+			if operation, found, err = s.child(pathItem, strings.ToLower(route.Method)); err == nil && found {
+				return operation, nil
+			}
+		}
+	}
+	if err != nil {
+		return schemaNode{}, err
+	}
+	return schemaNode{}, fmt.Errorf("operation %s %s not found", route.Method, route.Path)
+}
+
+// validateContent validates a body against the schema of the media type matching the content type
+func (s *Schemas) validateContent(container schemaNode, contentType string, body []byte, mismatch string) error {
+	content, found, err := s.child(container, "content")
+	if err != nil || !found {
+		return err
+	}
+	key := matchMediaType(content.value, contentType)
+	if key == "" {
+		return fmt.Errorf("%s: %q", mismatch, contentType)
+	}
+	media, _, err := s.child(content, key)
+	if err != nil {
+		return err
+	}
+	schemaLocation, found, err := s.child(media, "schema")
+	if err != nil || !found {
+		return err
+	}
+
+	schema, err := s.compile(schemaLocation)
+	if err != nil {
+		return err
+	}
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return errors.New("failed to decode body: " + err.Error())
+	}
+	err = schema.Validate(value)
+	var schemaErr *jsonschema.ValidationError
+	if errors.As(err, &schemaErr) {
+		validationErrors := s.validationErrors(schemaErr)
+		// The properties of an object are not validated in a stable order
+		sort.SliceStable(validationErrors, func(i, j int) bool {
+			return validationErrors[i].Title < validationErrors[j].Title
+		})
+		return &validator.BodyError{Errors: validationErrors}
+	}
+	return err
+}
+
+// matchMediaType returns the media type of content matching a content type, as kin-openapi does
+func matchMediaType(content any, contentType string) string {
+	object, _ := content.(map[string]any)
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	family, _, _ := strings.Cut(mediaType, "/")
+	for _, key := range []string{contentType, mediaType, family + "/*", "*/*"} {
+		if _, ok := object[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// compile compiles the schema at a location, the location may be a reference to it
+func (s *Schemas) compile(node schemaNode) (*jsonschema.Schema, error) {
+	location := node.document + "#" + encodePointer(node.pointer)
+	if schema, ok := s.compiled[location]; ok {
+		return schema, nil
+	}
+	schema, err := s.compiler.Compile(location)
+	if err != nil {
+		return nil, err
+	}
+	s.compiled[location] = schema
+	return schema, nil
+}
+
+// validationErrors lists the failed assertions of a validation, in the format of kin-openapi schema errors
+func (s *Schemas) validationErrors(err *jsonschema.ValidationError) []validator.ValidationError {
+	if len(err.Causes) > 0 {
+		var result []validator.ValidationError
+		for _, cause := range err.Causes {
+			result = append(result, s.validationErrors(cause)...)
+		}
+		return result
+	}
+
+	title := err.ErrorKind.LocalizedString(errorPrinter)
+	if len(err.InstanceLocation) > 0 {
+		title = fmt.Sprintf("Error at %q: %s", encodePointer(err.InstanceLocation), title)
+	}
+	validationError := validator.ValidationError{Title: title}
+	if schema, ok := s.lookup(err.SchemaURL); ok {
+		if schemaBytes, marshalErr := json.MarshalIndent(schema, "", "  "); marshalErr == nil {
+			validationError.Schema = string(schemaBytes)
+		}
+	}
+	return []validator.ValidationError{validationError}
+}
+
+// lookup returns the value at an absolute location of the spec
+func (s *Schemas) lookup(location string) (any, bool) {
+	documentLocation, fragment, _ := strings.Cut(location, "#")
+	document, ok := s.documents[documentLocation]
+	if !ok {
+		return nil, false
+	}
+	fragment, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, false
+	}
+	return walkPointer(document, fragment)
+}
+
+// child returns a value of an object, following its reference when it has one
+func (s *Schemas) child(parent schemaNode, key string) (schemaNode, bool, error) {
+	object, ok := parent.value.(map[string]any)
+	if !ok {
+		return schemaNode{}, false, nil
+	}
+	value, ok := object[key]
+	if !ok {
+		return schemaNode{}, false, nil
+	}
+	node := schemaNode{document: parent.document, pointer: append(append([]string{}, parent.pointer...), key), value: value}
+	if key == "schema" {
+		// Schemas are resolved by the compiler
+		return node, true, nil
+	}
+
+	for range 32 {
+		object, ok = node.value.(map[string]any)
+		if !ok {
+			return node, true, nil
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return node, true, nil
+		}
+		base, err := url.Parse(node.document)
+		if err != nil {
+			return schemaNode{}, false, err
+		}
+		target, err := base.Parse(ref)
+		if err != nil {
+			return schemaNode{}, false, err
+		}
+		fragment := target.Fragment
+		target.Fragment = ""
+		document, err := s.document(target.String())
+		if err != nil {
+			return schemaNode{}, false, err
+		}
+		value, found := walkPointer(document, fragment)
+		if !found {
+			return schemaNode{}, false, fmt.Errorf("reference %s not found", ref)
+		}
+		node = schemaNode{document: target.String(), pointer: splitPointer(fragment), value: value}
+	}
+	return schemaNode{}, false, errors.New("too many nested references at " + encodePointer(node.pointer))
+}
+
+// document returns a document of the spec, reading it on first use
+func (s *Schemas) document(location string) (any, error) {
+	if document, ok := s.documents[location]; ok {
+		return document, nil
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.read(parsed)
+	if err != nil {
+		return nil, err
+	}
+	document, err := decodeDocument(data)
+	if err != nil {
+		return nil, errors.New(location + ": " + err.Error())
+	}
+	s.documents[location] = document
+	return document, nil
+}
+
+// decodeDocument decodes a YAML or JSON document the way the jsonschema package expects it
+func decodeDocument(data []byte) (any, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(jsonData))
+}
+
+func walkPointer(document any, pointer string) (any, bool) {
+	current := document
+	for _, token := range splitPointer(pointer) {
+		switch value := current.(type) {
+		case map[string]any:
+			child, ok := value[token]
+			if !ok {
+				return nil, false
+			}
+			current = child
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func splitPointer(pointer string) []string {
+	if pointer == "" || pointer == "/" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+	return tokens
+}
+
+func encodePointer(tokens []string) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
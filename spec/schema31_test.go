@@ -0,0 +1,75 @@
+package spec
+
+import (
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"net/url"
+	"os"
+	"path/filepath"
+	"static-openapivalidator/validator"
+	"strings"
+	"testing"
+)
+
+func TestSchemas(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "user.yaml"), []byte(`type: object
+required: [name]
+properties:
+  name: {type: [string, "null"]}
+  kind: {const: person}
+  position: {type: array, prefixItems: [{type: number}, {type: number}], items: false}
+`), 0644)
+	data := []byte(`openapi: 3.1.0
+info: {title: test, version: "1"}
+paths:
+  /users:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "user.yaml"}
+      responses:
+        2XX:
+          $ref: "#/components/responses/User"
+components:
+  responses:
+    User:
+      description: ok
+      content:
+        application/json:
+          schema: {$ref: "user.yaml"}
+`)
+	read := func(location *url.URL) ([]byte, error) {
+		return openapi3.ReadFromFile(nil, location)
+	}
+	schemas, err := NewSchemas(&url.URL{Path: filepath.ToSlash(filepath.Join(dir, "spec.yaml"))}, data, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := &routers.Route{Path: "/users", Method: "POST"}
+
+	if !schemas.Handles("application/problem+json; charset=utf-8") || schemas.Handles("text/plain") {
+		t.Fatal("only JSON bodies should be handled")
+	}
+	if err = schemas.ValidateRequestBody(route, "application/json", []byte(`{"name": null, "kind": "person", "position": [1, 2]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = schemas.ValidateRequestBody(route, "application/json", nil); err == nil {
+		t.Fatal("required bodies should be checked")
+	}
+
+	err = schemas.ValidateResponseBody(route, 201, "application/json", []byte(`{"name": 1, "kind": "robot"}`))
+	var bodyError *validator.BodyError
+	if !errors.As(err, &bodyError) || len(bodyError.Errors) != 2 {
+		t.Fatal("schema violations should be reported", err)
+	}
+	if bodyError.Errors[0].Title != `Error at "/kind": value must be 'person'` || !strings.Contains(bodyError.Errors[0].Schema, `"const": "person"`) {
+		t.Fatal(bodyError.Errors[0])
+	}
+	if err = schemas.ValidateResponseBody(route, 201, "application/xml", []byte(`<user/>`)); err == nil {
+		t.Fatal("unexpected content types should fail")
+	}
+}
@@ -0,0 +1,64 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+	"net/url"
+	"strings"
+)
+
+// Swagger2Locations maps the locations of a converted Swagger 2.0 spec back to the ones of the original spec
+var Swagger2Locations = strings.NewReplacer(swagger2Locations...)
+
+var swagger2Locations = []string{
+	"#/components/schemas/", "#/definitions/",
+	"#/components/parameters/", "#/parameters/",
+	"#/components/requestBodies/", "#/parameters/",
+	"#/components/responses/", "#/responses/",
+}
+
+// IsSwagger2 tells whether a document is a Swagger 2.0 spec
+func IsSwagger2(data []byte) bool {
+	var header struct {
+		Swagger any `json:"swagger"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil || header.Swagger == nil {
+		return false
+	}
+	// An unquoted 2.0 is a number
+	version := fmt.Sprint(header.Swagger)
+	return version == "2" || version == "2.0"
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 spec to OpenAPI 3.0
+// The servers are built from host, basePath and schemes, and the body and formData parameters become request bodies
+// of the media types the operations consume
+func ConvertSwagger2(data []byte, loader *openapi3.Loader, location *url.URL) (*openapi3.T, error) {
+	var document map[string]any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	document["swagger"] = "2.0"
+	normalized, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var doc2 openapi2.T
+	if err = json.Unmarshal(normalized, &doc2); err != nil {
+		return nil, err
+	}
+
+	doc3, err := openapi2conv.ToV3WithLoader(&doc2, loader, location)
+	if err != nil {
+		return nil, err
+	}
+	if doc2.Host == "" && strings.Trim(doc2.BasePath, "/") != "" {
+		// Without a host, the base path is still the prefix of every path
+		doc3.AddServer(&openapi3.Server{URL: "/" + strings.Trim(doc2.BasePath, "/")})
+	}
+	return doc3, nil
+}
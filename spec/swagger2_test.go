@@ -0,0 +1,68 @@
+package spec
+
+import (
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"testing"
+)
+
+func TestConvertSwagger2(t *testing.T) {
+	data := []byte(`swagger: 2.0
+info: {title: legacy, version: "1"}
+basePath: /api/
+consumes: [application/json]
+produces: [application/json]
+paths:
+  /users:
+    post:
+      parameters:
+        - {name: user, in: body, required: true, schema: {$ref: "#/definitions/User"}}
+      responses:
+        201: {description: created, schema: {$ref: "#/definitions/User"}}
+  /avatars:
+    post:
+      consumes: [multipart/form-data]
+      parameters:
+        - {name: name, in: formData, required: true, type: string}
+        - {name: file, in: formData, required: true, type: file}
+      responses:
+        204: {description: uploaded}
+definitions:
+  User:
+    type: object
+    properties:
+      name: {type: string}
+`)
+	if !IsSwagger2(data) || IsSwagger2([]byte("openapi: 3.0.3\n")) {
+		t.Fatal("Swagger 2.0 specs should be detected")
+	}
+
+	loader := &openapi3.Loader{IsExternalRefsAllowed: true}
+	doc, err := ConvertSwagger2(data, loader, &url.URL{Path: "swagger.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/api" {
+		t.Fatal("the base path should be kept without a host", doc.Servers)
+	}
+	users := doc.Paths.Find("/users").Post
+	if users.RequestBody.Value.Content.Get("application/json").Schema.Ref != "#/components/schemas/User" {
+		t.Fatal("body parameters should be request bodies")
+	}
+	if users.Responses.Status(201).Value.Content.Get("application/json") == nil {
+		t.Fatal("responses should have the media types produced")
+	}
+	form := doc.Paths.Find("/avatars").Post.RequestBody.Value.Content.Get("multipart/form-data")
+	if form == nil || len(form.Schema.Value.Required) != 2 {
+		t.Fatal("formData parameters should be a form request body")
+	}
+
+	if located := Swagger2Locations.Replace("doesn't match schema #/components/schemas/User"); located != "doesn't match schema #/definitions/User" {
+		t.Fatal(located)
+	}
+}
@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"fmt"
+)
+
+// Transitions of an exchange from the current spec to the candidate one
+const (
+	// Regressed exchanges fail against the candidate spec only
+	Regressed = "regressed"
+	// Fixed exchanges fail against the current spec only
+	Fixed = "fixed"
+	// NewlyUnmatched exchanges are routed by the current spec only
+	NewlyUnmatched = "unmatched"
+	// Changed exchanges have another status against the candidate spec, without failing against either
+	Changed = "changed"
+)
+
+// CandidateOutcome is the outcome of an exchange against the candidate spec
+type CandidateOutcome struct {
+	Status       string `json:"status"`
+	ErrorSummary string `json:"error,omitempty"`
+	Route        string `json:"route,omitempty"`
+	// Transition is empty when the exchange has the same status against both specs
+	Transition string `json:"transition,omitempty"`
+}
+
+// Compare sets the outcome against the candidate spec on the results against the current spec
+// Both are validated from the same test results, so they come in the same order
+func Compare(current []ValidationResult, candidate []ValidationResult) error {
+	if len(current) != len(candidate) {
+		return fmt.Errorf("%d results against the current spec, %d against the candidate one", len(current), len(candidate))
+	}
+	for i := range current {
+		if current[i].GetTestId() != candidate[i].GetTestId() || current[i].GetType() != candidate[i].GetType() {
+			return fmt.Errorf("%s %s has no counterpart against the candidate spec", current[i].GetType(), current[i].GetTestId())
+		}
+		outcome := &CandidateOutcome{
+			Status:       candidate[i].GetStatus(),
+			ErrorSummary: candidate[i].GetErrorSummary(),
+			Route:        candidate[i].GetRoute(),
+			Transition:   transition(current[i], candidate[i]),
+		}
+		switch result := current[i].(type) {
+		case *RequestValidationResult:
+			result.Candidate = outcome
+		case *ResponseValidationResult:
+			result.Candidate = outcome
+		}
+	}
+	return nil
+}
+
+func transition(current ValidationResult, candidate ValidationResult) string {
+	switch {
+	case current.GetStatus() == candidate.GetStatus():
+		return ""
+	case current.GetRoute() != "" && candidate.GetRoute() == "":
+		return NewlyUnmatched
+	case candidate.GetStatus() == Failure:
+		return Regressed
+	case current.GetStatus() == Failure:
+		return Fixed
+	default:
+		return Changed
+	}
+}
@@ -3,7 +3,9 @@ package validator
 import (
 	"encoding/json"
 	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/gobwas/glob"
+	"strings"
 )
 
 type Config struct {
@@ -11,27 +13,77 @@ type Config struct {
 	IgnoredResponses []glob.Glob
 	IgnoredRoutes    []glob.Glob
 	IgnoreServers    bool
+	// IdTemplate is the template used to build test ids, when the parser supports it
+	IdTemplate string
 }
 
 type TestResult struct {
 	AdditionalInfos map[string]string
-	Request         *TestRequest
-	Response        *TestResponse
-	Id              string
+	// Assertions are the functional checks run by the test tool on the exchange
+	Assertions []Assertion
+	Request    *TestRequest
+	// Response is nil when only the request is validated
+	Response *TestResponse
+	Id       string
+}
+
+// Assertion is the outcome of a functional check, its status is either Success, Failure or Ignored
+type Assertion struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 type TestRequest struct {
 	*openapi3filter.RequestValidationInput
 	Body         string
+	BodyEncoding string
 	ParsingError string
 	Ignored      bool
+	// Spec is the name of the spec the request was routed to
+	Spec string
+	// Server is the URL of the server of the spec the request matched
+	Server string
+	// OriginalUrl is the URL of the request before it was rewritten, it is empty when no rewrite rule applied
+	OriginalUrl string
+	// OtherRoutes are the other paths of the spec the request matches, its route was preferred to them
+	OtherRoutes []string
+	// Unmatched is set when the request is bound to none of the specs
+	Unmatched bool
+	// BodyValidator validates the bodies in place of kin-openapi when set, such as for OpenAPI 3.1 specs
+	BodyValidator BodyValidator
+	// Locations maps the locations of the errors back to the original spec when set, such as for converted Swagger 2.0 specs
+	Locations *strings.Replacer
+}
+
+// BodyValidator validates the bodies of the content types it handles, kin-openapi still validates the rest of the exchange
+type BodyValidator interface {
+	Handles(contentType string) bool
+	ValidateRequestBody(route *routers.Route, contentType string, body []byte) error
+	ValidateResponseBody(route *routers.Route, status int, contentType string, body []byte) error
+}
+
+// BodyError is returned by a BodyValidator when a body does not match its schema
+type BodyError struct {
+	Errors []ValidationError
+}
+
+func (e *BodyError) Error() string {
+	var titles []string
+	for _, validationError := range e.Errors {
+		titles = append(titles, validationError.Title)
+	}
+	return strings.Join(titles, " | ")
 }
 
 type TestResponse struct {
 	*openapi3filter.ResponseValidationInput
 	Body         string
+	BodyEncoding string
 	ParsingError string
-	Ignored      bool
+	// TransportError is set when no response was received, such as on timeouts or refused connections
+	TransportError string
+	Ignored        bool
 }
 
 type ValidationResult interface {
@@ -41,8 +93,24 @@ type ValidationResult interface {
 	GetErrors() []ValidationError
 	GetUrl() string
 	GetBody() string
+	GetBodyEncoding() string
 	GetHeaders() map[string][]string
 	GetStatus() string
+	GetAssertions() []Assertion
+	GetAdditionalInfos() map[string]string
+	GetSpec() string
+	GetServer() string
+	// GetOriginalUrl and GetRewrittenUrl are the full URLs of the exchange before and after rewriting, when it was rewritten
+	GetOriginalUrl() string
+	GetRewrittenUrl() string
+	// GetRoute is the path of the spec the exchange was routed to, GetAmbiguousRoutes the other paths it matches
+	GetRoute() string
+	GetAmbiguousRoutes() []string
+	// GetSchemaSource is the file and JSON pointer of the schema of the body, when it is a $ref and the body fails it
+	// It is the $ref of the body schema itself, even when the failing schema is nested in it
+	GetSchemaSource() string
+	// GetCandidate is the outcome of the exchange against the candidate spec, when one is given
+	GetCandidate() *CandidateOutcome
 }
 
 type ValidationError struct {
@@ -51,14 +119,25 @@ type ValidationError struct {
 }
 
 type RequestValidationResult struct {
-	TestId       string
-	Url          string
-	ErrorSummary string
-	Errors       []ValidationError
-	Status       string
-	Body         string
-	Method       string
-	Headers      map[string][]string
+	TestId          string
+	Url             string
+	ErrorSummary    string
+	Errors          []ValidationError
+	Status          string
+	Body            string
+	BodyEncoding    string
+	Method          string
+	Headers         map[string][]string
+	Assertions      []Assertion
+	AdditionalInfos map[string]string
+	Spec            string
+	Server          string
+	OriginalUrl     string
+	RewrittenUrl    string
+	Route           string
+	AmbiguousRoutes []string
+	SchemaSource    string
+	Candidate       *CandidateOutcome
 }
 
 func (r RequestValidationResult) GetType() string {
@@ -85,6 +164,10 @@ func (r RequestValidationResult) GetBody() string {
 	return r.Body
 }
 
+func (r RequestValidationResult) GetBodyEncoding() string {
+	return r.BodyEncoding
+}
+
 func (r RequestValidationResult) GetHeaders() map[string][]string {
 	return r.Headers
 }
@@ -93,29 +176,91 @@ func (r RequestValidationResult) GetStatus() string {
 	return r.Status
 }
 
+func (r RequestValidationResult) GetAssertions() []Assertion {
+	return r.Assertions
+}
+
+func (r RequestValidationResult) GetAdditionalInfos() map[string]string {
+	return r.AdditionalInfos
+}
+
+func (r RequestValidationResult) GetSpec() string {
+	return r.Spec
+}
+
+func (r RequestValidationResult) GetServer() string {
+	return r.Server
+}
+
+func (r RequestValidationResult) GetOriginalUrl() string {
+	return r.OriginalUrl
+}
+
+func (r RequestValidationResult) GetRewrittenUrl() string {
+	return r.RewrittenUrl
+}
+
+func (r RequestValidationResult) GetRoute() string {
+	return r.Route
+}
+
+func (r RequestValidationResult) GetAmbiguousRoutes() []string {
+	return r.AmbiguousRoutes
+}
+
+func (r RequestValidationResult) GetSchemaSource() string {
+	return r.SchemaSource
+}
+
+func (r RequestValidationResult) GetCandidate() *CandidateOutcome {
+	return r.Candidate
+}
+
 func (r RequestValidationResult) MarshalJSON() ([]byte, error) {
 	return json.Marshal(jsonValidationResult{
-		TestId:       r.TestId,
-		Url:          r.Url,
-		ErrorSummary: r.ErrorSummary,
-		Errors:       r.Errors,
-		Status:       r.Status,
-		Type:         r.GetType(),
-		Body:         r.Body,
-		Headers:      r.Headers,
-		Method:       r.Method,
+		TestId:          r.TestId,
+		Url:             r.Url,
+		ErrorSummary:    r.ErrorSummary,
+		Errors:          r.Errors,
+		Status:          r.Status,
+		Type:            r.GetType(),
+		Body:            r.Body,
+		BodyEncoding:    r.BodyEncoding,
+		Headers:         r.Headers,
+		Method:          r.Method,
+		Assertions:      r.Assertions,
+		AdditionalInfos: r.AdditionalInfos,
+		Spec:            r.Spec,
+		Server:          r.Server,
+		OriginalUrl:     r.OriginalUrl,
+		RewrittenUrl:    r.RewrittenUrl,
+		Route:           r.Route,
+		AmbiguousRoutes: r.AmbiguousRoutes,
+		SchemaSource:    r.SchemaSource,
+		Candidate:       r.Candidate,
 	})
 }
 
 type ResponseValidationResult struct {
-	TestId       string
-	Url          string
-	ErrorSummary string
-	Errors       []ValidationError
-	Status       string
-	Body         string
-	Headers      map[string][]string
-	Code         int
+	TestId          string
+	Url             string
+	ErrorSummary    string
+	Errors          []ValidationError
+	Status          string
+	Body            string
+	BodyEncoding    string
+	Headers         map[string][]string
+	Code            int
+	Assertions      []Assertion
+	AdditionalInfos map[string]string
+	Spec            string
+	Server          string
+	OriginalUrl     string
+	RewrittenUrl    string
+	Route           string
+	AmbiguousRoutes []string
+	SchemaSource    string
+	Candidate       *CandidateOutcome
 }
 
 func (r ResponseValidationResult) GetType() string {
@@ -142,6 +287,10 @@ func (r ResponseValidationResult) GetBody() string {
 	return r.Body
 }
 
+func (r ResponseValidationResult) GetBodyEncoding() string {
+	return r.BodyEncoding
+}
+
 func (r ResponseValidationResult) GetHeaders() map[string][]string {
 	return r.Headers
 }
@@ -150,29 +299,91 @@ func (r ResponseValidationResult) GetStatus() string {
 	return r.Status
 }
 
+func (r ResponseValidationResult) GetAssertions() []Assertion {
+	return r.Assertions
+}
+
+func (r ResponseValidationResult) GetAdditionalInfos() map[string]string {
+	return r.AdditionalInfos
+}
+
+func (r ResponseValidationResult) GetSpec() string {
+	return r.Spec
+}
+
+func (r ResponseValidationResult) GetServer() string {
+	return r.Server
+}
+
+func (r ResponseValidationResult) GetOriginalUrl() string {
+	return r.OriginalUrl
+}
+
+func (r ResponseValidationResult) GetRewrittenUrl() string {
+	return r.RewrittenUrl
+}
+
+func (r ResponseValidationResult) GetRoute() string {
+	return r.Route
+}
+
+func (r ResponseValidationResult) GetAmbiguousRoutes() []string {
+	return r.AmbiguousRoutes
+}
+
+func (r ResponseValidationResult) GetSchemaSource() string {
+	return r.SchemaSource
+}
+
+func (r ResponseValidationResult) GetCandidate() *CandidateOutcome {
+	return r.Candidate
+}
+
 func (r ResponseValidationResult) MarshalJSON() ([]byte, error) {
 	return json.Marshal(jsonValidationResult{
-		TestId:       r.TestId,
-		Url:          r.Url,
-		ErrorSummary: r.ErrorSummary,
-		Errors:       r.Errors,
-		Status:       r.Status,
-		Type:         r.GetType(),
-		Body:         r.Body,
-		Headers:      r.Headers,
-		Code:         r.Code,
+		TestId:          r.TestId,
+		Url:             r.Url,
+		ErrorSummary:    r.ErrorSummary,
+		Errors:          r.Errors,
+		Status:          r.Status,
+		Type:            r.GetType(),
+		Body:            r.Body,
+		BodyEncoding:    r.BodyEncoding,
+		Headers:         r.Headers,
+		Code:            r.Code,
+		Assertions:      r.Assertions,
+		AdditionalInfos: r.AdditionalInfos,
+		Spec:            r.Spec,
+		Server:          r.Server,
+		OriginalUrl:     r.OriginalUrl,
+		RewrittenUrl:    r.RewrittenUrl,
+		Route:           r.Route,
+		AmbiguousRoutes: r.AmbiguousRoutes,
+		SchemaSource:    r.SchemaSource,
+		Candidate:       r.Candidate,
 	})
 }
 
 type jsonValidationResult struct {
-	TestId       string              `json:"id"`
-	Url          string              `json:"url"`
-	ErrorSummary string              `json:"error,omitempty"`
-	Errors       []ValidationError   `json:"errors,omitempty"`
-	Status       string              `json:"status"`
-	Type         string              `json:"type"`
-	Body         string              `json:"body,omitempty"`
-	Headers      map[string][]string `json:"headers,omitempty"`
-	Method       string              `json:"method,omitempty"`
-	Code         int                 `json:"code,omitempty"`
+	TestId          string              `json:"id"`
+	Url             string              `json:"url"`
+	ErrorSummary    string              `json:"error,omitempty"`
+	Errors          []ValidationError   `json:"errors,omitempty"`
+	Status          string              `json:"status"`
+	Type            string              `json:"type"`
+	Body            string              `json:"body,omitempty"`
+	BodyEncoding    string              `json:"bodyEncoding,omitempty"`
+	Headers         map[string][]string `json:"headers,omitempty"`
+	Method          string              `json:"method,omitempty"`
+	Code            int                 `json:"code,omitempty"`
+	Assertions      []Assertion         `json:"assertions,omitempty"`
+	AdditionalInfos map[string]string   `json:"infos,omitempty"`
+	Spec            string              `json:"spec,omitempty"`
+	Server          string              `json:"server,omitempty"`
+	OriginalUrl     string              `json:"originalUrl,omitempty"`
+	RewrittenUrl    string              `json:"rewrittenUrl,omitempty"`
+	Route           string              `json:"route,omitempty"`
+	AmbiguousRoutes []string            `json:"ambiguousRoutes,omitempty"`
+	SchemaSource    string              `json:"schemaSource,omitempty"`
+	Candidate       *CandidateOutcome   `json:"candidate,omitempty"`
 }
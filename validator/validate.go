@@ -6,7 +6,10 @@ import (
 	"errors"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
+	"io"
 	"static-openapivalidator/logger"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -14,6 +17,10 @@ const (
 	Warning = "warning"
 	Ignored = "ignored"
 	Success = "success"
+	// NotExecuted is the status of responses that were never received, because of a transport failure
+	NotExecuted = "not-executed"
+	// Unmatched is the status of exchanges bound to none of the specs
+	Unmatched = "unmatched"
 )
 
 func Validate(results []TestResult, ctx context.Context) ([]ValidationResult, error) {
@@ -52,6 +59,14 @@ func validateResult(result TestResult, ctx context.Context) ([]ValidationResult,
 }
 
 func computeError(err error) (string, []ValidationError, error) {
+	var bodyError *BodyError
+	if errors.As(err, &bodyError) {
+		errAsString := "Multiple errors"
+		if len(bodyError.Errors) == 1 {
+			errAsString = bodyError.Errors[0].Title
+		}
+		return errAsString, bodyError.Errors, nil
+	}
 	var multiError openapi3.MultiError
 	if errors.As(err, &multiError) {
 		return computeErrorFields(multiError)
@@ -134,64 +149,269 @@ func computeResultFields(validationError error) (string, string, []ValidationErr
 	return status, errorTitle, validationErrors, nil
 }
 
+// rewrittenUrl is the full URL of a request that was rewritten
+func rewrittenUrl(request *TestRequest) string {
+	if request.OriginalUrl == "" {
+		return ""
+	}
+	return request.Request.URL.String()
+}
+
+// routePath is the path of the spec a request was routed to
+func routePath(request *TestRequest) string {
+	if request.Route == nil {
+		return ""
+	}
+	return request.Route.Path
+}
+
+// locateSource maps the location of a schema back to the original spec
+func locateSource(locations *strings.Replacer, source string) string {
+	if locations == nil {
+		return source
+	}
+	return locations.Replace(source)
+}
+
+// requestSchemaSource is where the schema of a request body failing validation was loaded from, as file#pointer
+// It is empty when the body does not fail its schema, or when the schema is not a $ref
+// Only the $ref of the body schema is known: a body failing a nested schema is reported with the $ref of the body schema
+func requestSchemaSource(request *TestRequest, validationErr error) string {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(validationErr, &requestErr) || !strings.HasPrefix(requestErr.Reason, "doesn't match schema") {
+		return ""
+	}
+	requestBody := request.Route.Operation.RequestBody
+	if requestBody == nil || requestBody.Value == nil {
+		return ""
+	}
+	return mediaSchemaSource(requestBody.Value.Content, request.Request.Header.Get("Content-Type"))
+}
+
+// responseSchemaSource is where the schema of a response body failing validation was loaded from, as file#pointer
+// Like requestSchemaSource, it is the $ref of the body schema itself, not the one of a nested schema failing
+func responseSchemaSource(request *TestRequest, response *TestResponse, validationErr error) string {
+	var responseErr *openapi3filter.ResponseError
+	if !errors.As(validationErr, &responseErr) || !strings.HasPrefix(responseErr.Reason, "response body doesn't match schema") {
+		return ""
+	}
+	operation := request.Route.Operation
+	if operation.Responses == nil {
+		return ""
+	}
+	responseRef := operation.Responses.Value(ResponseStatus(operation, response.Status))
+	if responseRef == nil || responseRef.Value == nil {
+		return ""
+	}
+	return mediaSchemaSource(responseRef.Value.Content, response.Header.Get("Content-Type"))
+}
+
+// ResponseStatus is the response of an operation a status code is validated against, such as 200, 2XX or default
+func ResponseStatus(operation *openapi3.Operation, code int) string {
+	status := strconv.Itoa(code)
+	if operation == nil || operation.Responses == nil || operation.Responses.Value(status) != nil {
+		return status
+	}
+	if statusRange := status[:1] + "XX"; operation.Responses.Value(statusRange) != nil {
+		return statusRange
+	}
+	return "default"
+}
+
+func mediaSchemaSource(content openapi3.Content, contentType string) string {
+	mediaType := content.Get(contentType)
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Ref == "" || mediaType.Schema.RefPath() == nil {
+		return ""
+	}
+	return mediaType.Schema.RefPath().String()
+}
+
+// locateErrors maps the locations given in errors back to the original spec
+func locateErrors(locations *strings.Replacer, errAsString string, validationErrors []ValidationError) (string, []ValidationError) {
+	if locations == nil {
+		return errAsString, validationErrors
+	}
+	for i := range validationErrors {
+		validationErrors[i].Title = locations.Replace(validationErrors[i].Title)
+		validationErrors[i].Schema = locations.Replace(validationErrors[i].Schema)
+	}
+	return locations.Replace(errAsString), validationErrors
+}
+
 func requestValidationResult(result TestResult, ctx context.Context) (*RequestValidationResult, error) {
-	var status, errAsString string
+	var status, errAsString, schemaSource string
 	var validationErrors []ValidationError
 	var err error
 
 	if result.Request.Ignored {
 		status = Ignored
+	} else if result.Request.Unmatched {
+		status = Unmatched
+		errAsString = result.Request.ParsingError
 	} else {
 		if result.Request.ParsingError != "" {
 			status = Warning
 			errAsString = result.Request.ParsingError
 		} else {
-			status, errAsString, validationErrors, err = computeResultFields(openapi3filter.ValidateRequest(ctx, result.Request.RequestValidationInput))
+			validationErr := validateRequest(ctx, result.Request)
+			status, errAsString, validationErrors, err = computeResultFields(validationErr)
 			if err != nil {
 				return nil, err
 			}
+			errAsString, validationErrors = locateErrors(result.Request.Locations, errAsString, validationErrors)
+			schemaSource = locateSource(result.Request.Locations, requestSchemaSource(result.Request, validationErr))
 		}
 	}
 
 	return &RequestValidationResult{
-		TestId:       result.Id,
-		Url:          result.Request.Request.URL.Path,
-		ErrorSummary: errAsString,
-		Errors:       validationErrors,
-		Status:       status,
-		Body:         result.Request.Body,
-		Headers:      result.Request.Request.Header,
-		Method:       result.Request.Request.Method,
+		TestId:          result.Id,
+		Url:             result.Request.Request.URL.Path,
+		ErrorSummary:    errAsString,
+		Errors:          validationErrors,
+		Status:          status,
+		Body:            result.Request.Body,
+		BodyEncoding:    result.Request.BodyEncoding,
+		Headers:         result.Request.Request.Header,
+		Method:          result.Request.Request.Method,
+		Assertions:      result.Assertions,
+		AdditionalInfos: result.AdditionalInfos,
+		Spec:            result.Request.Spec,
+		Server:          result.Request.Server,
+		OriginalUrl:     result.Request.OriginalUrl,
+		RewrittenUrl:    rewrittenUrl(result.Request),
+		Route:           routePath(result.Request),
+		AmbiguousRoutes: result.Request.OtherRoutes,
+		SchemaSource:    schemaSource,
 	}, nil
 }
 
 func responseValidationResult(result TestResult, ctx context.Context) (*ResponseValidationResult, error) {
-	var status, errAsString string
+	var status, errAsString, schemaSource string
 	var validationErrors []ValidationError
 	var err error
 
+	if result.Response == nil {
+		// Request only result, such as a request definition without any example response
+		return nil, nil
+	}
 	if result.Response.Ignored {
 		status = Ignored
+	} else if result.Response.TransportError != "" {
+		status = NotExecuted
+		errAsString = result.Response.TransportError
+	} else if result.Request.Unmatched {
+		status = Unmatched
+		errAsString = result.Request.ParsingError
 	} else {
 		if result.Response.ParsingError != "" {
 			status = Warning
 			errAsString = result.Response.ParsingError
 		} else {
-			status, errAsString, validationErrors, err = computeResultFields(openapi3filter.ValidateResponse(ctx, result.Response.ResponseValidationInput))
+			validationErr := validateResponse(ctx, result.Request, result.Response)
+			status, errAsString, validationErrors, err = computeResultFields(validationErr)
 			if err != nil {
 				return nil, err
 			}
+			errAsString, validationErrors = locateErrors(result.Request.Locations, errAsString, validationErrors)
+			schemaSource = locateSource(result.Request.Locations, responseSchemaSource(result.Request, result.Response, validationErr))
 		}
 	}
 
 	return &ResponseValidationResult{
-		TestId:       result.Id,
-		Url:          result.Request.Request.URL.Path,
-		ErrorSummary: errAsString,
-		Errors:       validationErrors,
-		Status:       status,
-		Code:         result.Response.Status,
-		Body:         result.Response.Body,
-		Headers:      result.Response.ResponseValidationInput.Header,
+		TestId:          result.Id,
+		Url:             result.Request.Request.URL.Path,
+		ErrorSummary:    errAsString,
+		Errors:          validationErrors,
+		Status:          status,
+		Code:            result.Response.Status,
+		Body:            result.Response.Body,
+		BodyEncoding:    result.Response.BodyEncoding,
+		Headers:         result.Response.ResponseValidationInput.Header,
+		Assertions:      result.Assertions,
+		AdditionalInfos: result.AdditionalInfos,
+		Spec:            result.Request.Spec,
+		Server:          result.Request.Server,
+		OriginalUrl:     result.Request.OriginalUrl,
+		RewrittenUrl:    rewrittenUrl(result.Request),
+		Route:           routePath(result.Request),
+		AmbiguousRoutes: result.Request.OtherRoutes,
+		SchemaSource:    schemaSource,
 	}, nil
 }
+
+// validateRequest validates a request with kin-openapi, leaving its body to the BodyValidator of the request when it handles it
+// A body excluded by the options of the request, such as an unrecorded file, is validated by neither
+func validateRequest(ctx context.Context, request *TestRequest) error {
+	input := *request.RequestValidationInput
+	contentType := input.Request.Header.Get("Content-Type")
+	bodyExcluded := input.Options != nil && input.Options.ExcludeRequestBody
+	if request.BodyValidator == nil || !request.BodyValidator.Handles(contentType) || bodyExcluded {
+		return openapi3filter.ValidateRequest(ctx, &input)
+	}
+
+	input.Options = withoutBodies(input.Options)
+	if err := openapi3filter.ValidateRequest(ctx, &input); err != nil {
+		return err
+	}
+	var body []byte
+	if input.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(input.Request.Body); err != nil {
+			return err
+		}
+	}
+	err := request.BodyValidator.ValidateRequestBody(input.Route, contentType, body)
+	if err == nil {
+		return nil
+	}
+	requestErr := &openapi3filter.RequestError{Input: &input, Err: err}
+	if input.Route.Operation.RequestBody != nil {
+		requestErr.RequestBody = input.Route.Operation.RequestBody.Value
+	}
+	var bodyError *BodyError
+	if errors.As(err, &bodyError) {
+		requestErr.Reason = "doesn't match schema"
+	}
+	return requestErr
+}
+
+// validateResponse validates a response with kin-openapi, leaving its body to the BodyValidator of the request when it handles it
+func validateResponse(ctx context.Context, request *TestRequest, response *TestResponse) error {
+	input := *response.ResponseValidationInput
+	contentType := input.Header.Get("Content-Type")
+	if request.BodyValidator == nil || !request.BodyValidator.Handles(contentType) {
+		return openapi3filter.ValidateResponse(ctx, &input)
+	}
+
+	input.Options = withoutBodies(input.Options)
+	if err := openapi3filter.ValidateResponse(ctx, &input); err != nil {
+		return err
+	}
+	var body []byte
+	if input.Body != nil {
+		var err error
+		if body, err = io.ReadAll(input.Body); err != nil {
+			return err
+		}
+	}
+	err := request.BodyValidator.ValidateResponseBody(input.RequestValidationInput.Route, input.Status, contentType, body)
+	if err == nil {
+		return nil
+	}
+	responseErr := &openapi3filter.ResponseError{Input: &input, Err: err}
+	var bodyError *BodyError
+	if errors.As(err, &bodyError) {
+		responseErr.Reason = "response body doesn't match schema"
+	}
+	return responseErr
+}
+
+func withoutBodies(options *openapi3filter.Options) *openapi3filter.Options {
+	excluded := openapi3filter.Options{}
+	if options != nil {
+		excluded = *options
+	}
+	excluded.ExcludeRequestBody = true
+	excluded.ExcludeResponseBody = true
+	return &excluded
+}